@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"errors"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (apikey_middleware.go)
+
+const (
+	ApiKeyHeader    = "X-API-Key"
+	apiKeyChallenge = `ApiKey header="X-API-Key"`
+)
+
+// NewApiKeyMiddleware authenticates the X-API-Key header and puts the principal into the user context.
+// Requests without the header are passed on untouched, so it can be chained in front of the JWT middleware
+func NewApiKeyMiddleware(service core.ApiKeyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rawKey := c.Get(ApiKeyHeader)
+		if rawKey == "" {
+			return c.Next()
+		}
+
+		// call AuthenticateApiKey() to verify the key in service and check Error
+		principal, err := service.AuthenticateApiKey(c.UserContext(), rawKey)
+		if err != nil {
+			if errors.Is(err, core.ErrInvalidApiKey) {
+				return unauthorized(c, apiKeyChallenge, err)
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		setPrincipal(c, principal)
+
+		return c.Next()
+	}
+}
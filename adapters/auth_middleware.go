@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (auth_middleware.go)
+
+var ErrMissingRole = errors.New("missing required role")
+
+const principalLocalsKey = "principal"
+
+// setPrincipal makes the principal available to handlers (Locals) and to core (user context)
+func setPrincipal(c *fiber.Ctx, principal *core.Principal) {
+	c.Locals(principalLocalsKey, principal)
+	c.SetUserContext(core.WithPrincipal(c.UserContext(), principal))
+}
+
+// unauthorized writes a 401 response with the given WWW-Authenticate challenge
+func unauthorized(c *fiber.Ctx, challenge string, err error) error {
+	c.Set(fiber.HeaderWWWAuthenticate, challenge)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+}
+
+// NewRequireRoleMiddleware only lets principals with the given role through, it must run after an authenticator
+func NewRequireRoleMiddleware(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := c.Locals(principalLocalsKey).(*core.Principal)
+		if !ok {
+			return unauthorized(c, bearerChallenge, ErrMissingToken)
+		}
+		if !slices.Contains(principal.Roles, role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": ErrMissingRole.Error()})
+		}
+
+		return c.Next()
+	}
+}
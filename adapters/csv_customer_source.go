@@ -0,0 +1,94 @@
+package adapters
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// * Secondary adapter (csv_customer_source.go)
+
+var (
+	ErrCSVMissingColumn = errors.New("csv header is missing a required column")
+	ErrCSVInvalidAge    = errors.New("age must be a whole number")
+)
+
+// csvRequiredColumns are matched case-insensitively against the header, other columns are ignored
+var csvRequiredColumns = []string{"name", "age"}
+
+// CSVCustomerSource streams customers from a CSV file with a header row, the columns may be in any order
+type CSVCustomerSource struct {
+	reader  *csv.Reader
+	columns map[string]int
+	width   int
+}
+
+// NewCSVCustomerSource reads the header and fails when a required column is missing
+func NewCSVCustomerSource(r io.Reader) (*CSVCustomerSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	// Read the header and check Error
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %s", ErrCSVMissingColumn, strings.Join(csvRequiredColumns, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for index, column := range header {
+		if index == 0 {
+			// spreadsheet programs often start the file with a byte order mark
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(column))] = index
+	}
+	width := 0
+	for _, column := range csvRequiredColumns {
+		index, ok := columns[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrCSVMissingColumn, column)
+		}
+		width = max(width, index+1)
+	}
+
+	return &CSVCustomerSource{reader: reader, columns: columns, width: width}, nil
+}
+
+func (s *CSVCustomerSource) Next() (core.ImportRow, error) {
+	record, err := s.reader.Read()
+	if err != nil {
+		// a malformed row is reported on its own, the rows after it are still read
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return core.ImportRow{Line: parseErr.StartLine, Err: parseErr.Err}, nil
+		}
+		return core.ImportRow{}, err
+	}
+
+	line, _ := s.reader.FieldPos(0)
+	row := core.ImportRow{Line: line}
+	if len(record) < s.width {
+		row.Err = csv.ErrFieldCount
+		return row, nil
+	}
+
+	row.Customer.Name = strings.TrimSpace(record[s.columns["name"]])
+	age, err := strconv.ParseUint(strings.TrimSpace(record[s.columns["age"]]), 10, 32)
+	if err != nil {
+		row.Err = ErrCSVInvalidAge
+		return row, nil
+	}
+	row.Customer.Age = uint(age)
+
+	return row, nil
+}
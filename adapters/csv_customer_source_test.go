@@ -0,0 +1,64 @@
+package adapters
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVCustomerSource(t *testing.T) {
+	// Success case
+	t.Run("successful with columns in any order", func(t *testing.T) {
+		source, err := NewCSVCustomerSource(strings.NewReader("\ufeffAge, Name ,email\n24,Fiat,fiat@example.com\n40,\"Anfat Nilaingan\",\n"))
+		require.NoError(t, err)
+
+		row, err := source.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, core.ImportRow{Line: 2, Customer: core.Customer{Name: "Fiat", Age: 24}}, row)
+
+		row, err = source.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, core.ImportRow{Line: 3, Customer: core.Customer{Name: "Anfat Nilaingan", Age: 40}}, row)
+
+		_, err = source.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("successful with row errors", func(t *testing.T) {
+		source, err := NewCSVCustomerSource(strings.NewReader("name,age\nFiat,old\nAnfat\nbad \"quote,1\nSomchai,30\n"))
+		require.NoError(t, err)
+
+		row, _ := source.Next()
+		assert.Equal(t, 2, row.Line)
+		assert.Equal(t, ErrCSVInvalidAge, row.Err)
+
+		row, _ = source.Next()
+		assert.Equal(t, 3, row.Line)
+		assert.Equal(t, csv.ErrFieldCount, row.Err)
+
+		row, _ = source.Next()
+		assert.Equal(t, 4, row.Line)
+		assert.Equal(t, csv.ErrBareQuote, row.Err)
+
+		// rows after a malformed row are still read
+		row, err = source.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, core.ImportRow{Line: 5, Customer: core.Customer{Name: "Somchai", Age: 30}}, row)
+	})
+
+	// Failure case
+	t.Run("(fail) missing column", func(t *testing.T) {
+		_, err := NewCSVCustomerSource(strings.NewReader("name\nFiat\n"))
+		assert.ErrorIs(t, err, ErrCSVMissingColumn)
+	})
+
+	t.Run("(fail) empty file", func(t *testing.T) {
+		_, err := NewCSVCustomerSource(strings.NewReader(""))
+		assert.ErrorIs(t, err, ErrCSVMissingColumn)
+	})
+}
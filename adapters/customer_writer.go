@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// customerEncoder writes the customers of an export in one format
+type customerEncoder interface {
+	begin() error
+	encode(customer *core.Customer) error
+	flush() error
+	end() error
+}
+
+// WriteCustomers writes the customers of stream to w as JSON array, NDJSON or CSV by format (one of
+// fiber.MIMEApplicationJSON, MIMEApplicationNDJSON and MIMETextCSV), w is flushed after every batch
+func WriteCustomers(w *bufio.Writer, format string, stream core.CustomerStream) error {
+	encoder := newCustomerEncoder(format, w)
+	if err := encoder.begin(); err != nil {
+		return err
+	}
+
+	err := stream(func(customers []core.Customer) error {
+		for index := range customers {
+			if err := encoder.encode(&customers[index]); err != nil {
+				return err
+			}
+		}
+		// send the batch, an error means the reader is gone and stops the stream
+		if err := encoder.flush(); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := encoder.end(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func newCustomerEncoder(format string, w *bufio.Writer) customerEncoder {
+	switch format {
+	case MIMETextCSV:
+		return &csvCustomerEncoder{writer: csv.NewWriter(w)}
+	case MIMEApplicationNDJSON:
+		return &ndjsonCustomerEncoder{encoder: json.NewEncoder(w)}
+	default:
+		return &jsonCustomerEncoder{w: w, encoder: json.NewEncoder(w)}
+	}
+}
+
+type jsonCustomerEncoder struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+func (e *jsonCustomerEncoder) begin() error {
+	return e.w.WriteByte('[')
+}
+
+func (e *jsonCustomerEncoder) encode(customer *core.Customer) error {
+	if e.count > 0 {
+		if err := e.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.encoder.Encode(customer)
+}
+
+func (e *jsonCustomerEncoder) flush() error {
+	return nil
+}
+
+func (e *jsonCustomerEncoder) end() error {
+	_, err := e.w.WriteString("]\n")
+	return err
+}
+
+type ndjsonCustomerEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonCustomerEncoder) begin() error {
+	return nil
+}
+
+func (e *ndjsonCustomerEncoder) encode(customer *core.Customer) error {
+	return e.encoder.Encode(customer)
+}
+
+func (e *ndjsonCustomerEncoder) flush() error {
+	return nil
+}
+
+func (e *ndjsonCustomerEncoder) end() error {
+	return nil
+}
+
+type csvCustomerEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvCustomerEncoder) begin() error {
+	// the columns match the ones read by the CSV import
+	return e.writer.Write([]string{"id", "name", "age"})
+}
+
+func (e *csvCustomerEncoder) encode(customer *core.Customer) error {
+	return e.writer.Write([]string{
+		strconv.FormatUint(uint64(customer.ID), 10),
+		customer.Name,
+		strconv.FormatUint(uint64(customer.Age), 10),
+	})
+}
+
+func (e *csvCustomerEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvCustomerEncoder) end() error {
+	return e.flush()
+}
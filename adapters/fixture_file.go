@@ -0,0 +1,46 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureFile lists the customers of a seed, YAML is read so JSON files work as well
+//
+//	customers:
+//	  - name: Fiat
+//	    age: 24
+type fixtureFile struct {
+	Customers []customerFixture `yaml:"customers"`
+}
+
+type customerFixture struct {
+	Name string `yaml:"name"`
+	Age  uint   `yaml:"age"`
+}
+
+// LoadCustomerFixtures reads the customers of a fixture file
+func LoadCustomerFixtures(path string) ([]core.Customer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file fixtureFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid fixture file: %w", err)
+	}
+	if len(file.Customers) == 0 {
+		return nil, errors.New("invalid fixture file: no customers")
+	}
+
+	customers := make([]core.Customer, len(file.Customers))
+	for index, fixture := range file.Customers {
+		customers[index] = core.Customer{Name: fixture.Name, Age: fixture.Age}
+	}
+	return customers, nil
+}
@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCustomerFixtures(t *testing.T) {
+	// Success case
+	t.Run("successful yaml", func(t *testing.T) {
+		path := writeTestFile(t, "customers.yaml", "customers:\n  - name: Fiat\n    age: 24\n")
+
+		customers, err := LoadCustomerFixtures(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []core.Customer{{Name: "Fiat", Age: 24}}, customers)
+	})
+
+	t.Run("successful json", func(t *testing.T) {
+		path := writeTestFile(t, "customers.json", `{"customers": [{"name": "Anfat", "age": 40}]}`)
+
+		customers, err := LoadCustomerFixtures(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []core.Customer{{Name: "Anfat", Age: 40}}, customers)
+	})
+
+	t.Run("successful repository fixtures", func(t *testing.T) {
+		customers, err := LoadCustomerFixtures("../fixtures/customers.yaml")
+		assert.NoError(t, err)
+		assert.Len(t, customers, 2)
+	})
+
+	// Failure case
+	t.Run("(fail) no customers", func(t *testing.T) {
+		path := writeTestFile(t, "customers.yaml", "customers: []\n")
+
+		_, err := LoadCustomerFixtures(path)
+		assert.Equal(t, "invalid fixture file: no customers", err.Error())
+	})
+
+	t.Run("(fail) invalid age", func(t *testing.T) {
+		path := writeTestFile(t, "customers.yaml", "customers:\n  - name: Fiat\n    age: old\n")
+
+		_, err := LoadCustomerFixtures(path)
+		assert.ErrorContains(t, err, "invalid fixture file")
+	})
+}
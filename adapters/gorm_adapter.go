@@ -1,7 +1,7 @@
 package adapters
 
 import (
-	"errors"
+	"context"
 
 	"github.com/fiatfour/itmx-crud-hex/core"
 	"gorm.io/gorm"
@@ -13,65 +13,111 @@ type GormCustomerRepository struct {
 	db *gorm.DB
 }
 
+// createBatchSize is the number of rows inserted by one statement of CreateInBatches
+const createBatchSize = 500
+
 func NewGormCustomerRepository(db *gorm.DB) core.CustomerRepository {
 	return &GormCustomerRepository{db: db}
 }
 
-func (r *GormCustomerRepository) Save(customer core.Customer) error {
+// scoped starts every query of the repository, rows of other tenants are never visible
+func (r *GormCustomerRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Where("tenant_id = ?", core.TenantFromContext(ctx))
+}
+
+func (r *GormCustomerRepository) Save(ctx context.Context, customer *core.Customer) error {
 	var count int64
 
-	// Check name is exists or not and check Error
-	if err := r.db.Model(&core.Customer{}).Where("name = ?", customer.Name).Count(&count).Error; err != nil {
+	// Check name is exists or not in the tenant and check Error
+	if err := r.scoped(ctx).Model(&core.Customer{}).Where("name = ?", customer.Name).Count(&count).Error; err != nil {
 		return err
 	}
 
 	if count > 0 {
-		return errors.New("name already exists")
-	}
-
-	// Insert Customer in database and check Error
-	if err := r.db.Create(&customer).Error; err != nil {
-		// Handle database errors
-		return err
+		return core.ErrNameAlreadyExists
 	}
 
-	return nil
+	// Insert Customer into the tenant of ctx with its change record in database and check Error
+	customer.TenantID = core.TenantFromContext(ctx)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(customer).Error; err != nil {
+			// Handle database errors
+			return translateCustomerError(err)
+		}
+		return appendChange(ctx, tx, core.ChangeCreate, customer.ID, customer)
+	})
 }
 
-func (r *GormCustomerRepository) Get(customerId uint) (*core.Customer, error) {
+func (r *GormCustomerRepository) Get(ctx context.Context, customerId uint) (*core.Customer, error) {
 	var customer core.Customer
 
 	// Get a Customer from database and check Error
-	if err := r.db.First(&customer, customerId).Error; err != nil {
+	if err := r.scoped(ctx).First(&customer, customerId).Error; err != nil {
 		return &core.Customer{}, err
 	}
 
 	return &customer, nil
 }
 
-func (r *GormCustomerRepository) GetAll() ([]core.Customer, error) {
+func (r *GormCustomerRepository) GetAll(ctx context.Context) ([]core.Customer, error) {
 	var customers []core.Customer
 
 	// Get all Customers from database and check Error
-	if err := r.db.Find(&customers).Error; err != nil {
+	if err := r.scoped(ctx).Order("id").Find(&customers).Error; err != nil {
 		return []core.Customer{}, err
 	}
 
 	return customers, nil
 }
 
-func (r *GormCustomerRepository) Update(customerId uint, customer *core.Customer) (*core.Customer, error) {
+func (r *GormCustomerRepository) ForEachBatch(ctx context.Context, batchSize int, fn func(customers []core.Customer) error) error {
+	var customers []core.Customer
+
+	// Read the Customers page by page on the primary key, only one page is held in memory, and check Error
+	result := r.scoped(ctx).FindInBatches(&customers, batchSize, func(tx *gorm.DB, batch int) error {
+		// stop between the batches once ctx is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(customers)
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *GormCustomerRepository) Update(ctx context.Context, customerId uint, customer *core.Customer) (*core.Customer, error) {
 	var count int64
-	// Check name is exists or not except the customerId for update and check Error
-	if err := r.db.Model(&core.Customer{}).Where("id != ? AND name = ?", customer.ID, customer.Name).Count(&count).Error; err != nil {
+	// Check name is exists or not in the tenant except the customerId for update and check Error
+	if err := r.scoped(ctx).Model(&core.Customer{}).Where("id != ? AND name = ?", customerId, customer.Name).Count(&count).Error; err != nil {
 		return &core.Customer{}, err
 	}
 	if count > 0 {
-		return &core.Customer{}, errors.New("name already exists")
+		return &core.Customer{}, core.ErrNameAlreadyExists
 	}
 
-	// Update a Customer in database and check Error
-	if err := r.db.Model(&core.Customer{}).Where("id = ?", customerId).Updates(customer).Error; err != nil {
+	// Update a Customer in database with its change record and check Error, the tenant can not be changed by
+	// an update
+	customer.TenantID = ""
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&core.Customer{}).Where("tenant_id = ? AND id = ?", core.TenantFromContext(ctx), customerId).Updates(customer)
+		if result.Error != nil {
+			return translateCustomerError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// the change record keeps every field after the update, not only the updated ones
+		var updated core.Customer
+		if err := tx.First(&updated, customerId).Error; err != nil {
+			return err
+		}
+		return appendChange(ctx, tx, core.ChangeUpdate, customerId, &updated)
+	})
+	if err != nil {
 		return &core.Customer{}, err
 	}
 	customer.ID = uint(customerId)
@@ -79,20 +125,224 @@ func (r *GormCustomerRepository) Update(customerId uint, customer *core.Customer
 	return customer, nil
 }
 
-func (r *GormCustomerRepository) Delete(customerId uint) error {
-	// Delete a Customer in database from customerId and check Error
-	if err := r.db.Where("id = ?", customerId).Delete(&core.Customer{}).Error; err != nil {
+func (r *GormCustomerRepository) Delete(ctx context.Context, customerId uint) error {
+	// Delete a Customer in database from customerId with its tombstone and check Error, a missing customer is
+	// gorm.ErrRecordNotFound so the caller records no change for it
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteCustomer(ctx, tx, customerId)
+	})
+}
+
+// deleteCustomer deletes a Customer of the tenant with its tombstone, it returns gorm.ErrRecordNotFound when the
+// customer is missing or belongs to another tenant
+func deleteCustomer(ctx context.Context, tx *gorm.DB, customerId uint) error {
+	result := tx.Where("tenant_id = ? AND id = ?", core.TenantFromContext(ctx), customerId).Delete(&core.Customer{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return appendChange(ctx, tx, core.ChangeDelete, customerId, nil)
+}
+
+func (r *GormCustomerRepository) Search(ctx context.Context, customerId uint) error {
+	// Search a Customer in database from customerId and check Error
+	if err := r.scoped(ctx).First(&core.Customer{}, customerId).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *GormCustomerRepository) Search(customerId uint) error {
-	// Search a Customer in database from customerId and check Error
-	if err := r.db.First(&core.Customer{}, customerId).Error; err != nil {
+func (r *GormCustomerRepository) ApplyBatch(ctx context.Context, operations []core.BatchOperation) ([]uint, error) {
+	ids := make([]uint, len(operations))
+
+	// Run every operation in one transaction, the first error rolls back the whole batch
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := &GormCustomerRepository{db: tx}
+
+		for start := 0; start < len(operations); {
+			operation := operations[start]
+			switch operation.Operation {
+			case core.BatchOperationCreate:
+				// consecutive creates are inserted together with CreateInBatches
+				end := start
+				for end < len(operations) && operations[end].Operation == core.BatchOperationCreate {
+					end++
+				}
+				if err := txRepo.createBatch(ctx, operations[start:end], ids[start:end], start); err != nil {
+					return err
+				}
+				start = end
+				continue
+
+			case core.BatchOperationUpdate:
+				customer := operation.Customer
+				if _, err := txRepo.Update(ctx, operation.ID, &customer); err != nil {
+					return &core.BatchItemError{Index: start, Err: err}
+				}
+
+			case core.BatchOperationDelete:
+				// a missing customer fails the operation, so no audit entry or event is written for it
+				if err := deleteCustomer(ctx, tx, operation.ID); err != nil {
+					return &core.BatchItemError{Index: start, Err: err}
+				}
+
+			default:
+				return &core.BatchItemError{Index: start, Err: core.ErrInvalidBatchOperation}
+			}
+
+			ids[start] = operation.ID
+			start++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []uint{}, err
+	}
+
+	return ids, nil
+}
+
+// createBatch inserts the customers of consecutive create operations, offset is the index of the first one in the batch
+func (r *GormCustomerRepository) createBatch(ctx context.Context, operations []core.BatchOperation, ids []uint, offset int) error {
+	tenantId := core.TenantFromContext(ctx)
+	customers := make([]core.Customer, len(operations))
+	seen := make(map[string]bool, len(operations))
+	for index, operation := range operations {
+		// Check name is unique inside the batch
+		if seen[operation.Customer.Name] {
+			return &core.BatchItemError{Index: offset + index, Err: core.ErrNameAlreadyExists}
+		}
+		seen[operation.Customer.Name] = true
+
+		customers[index] = core.Customer{TenantID: tenantId, Name: operation.Customer.Name, Age: operation.Customer.Age}
+	}
+
+	for start := 0; start < len(customers); start += createBatchSize {
+		chunk := customers[start:min(start+createBatchSize, len(customers))]
+		names := make([]string, len(chunk))
+		for index, customer := range chunk {
+			names[index] = customer.Name
+		}
+
+		// Check names are exists or not in the tenant and check Error
+		var existing []string
+		if err := r.scoped(ctx).Model(&core.Customer{}).Where("name IN ?", names).Pluck("name", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			exists := make(map[string]bool, len(existing))
+			for _, name := range existing {
+				exists[name] = true
+			}
+			for index, customer := range chunk {
+				if exists[customer.Name] {
+					return &core.BatchItemError{Index: offset + start + index, Err: core.ErrNameAlreadyExists}
+				}
+			}
+		}
+	}
+
+	// Insert Customers in batches with their change records and check Error
+	if err := r.db.WithContext(ctx).CreateInBatches(customers, createBatchSize).Error; err != nil {
+		return translateCustomerError(err)
+	}
+	first, err := nextChangeSequences(r.db.WithContext(ctx), len(customers))
+	if err != nil {
+		return err
+	}
+	changes := make([]*core.ChangeRecord, len(customers))
+	for index := range customers {
+		ids[index] = customers[index].ID
+		changes[index] = core.NewChangeRecord(ctx, core.ChangeCreate, customers[index].ID, &customers[index])
+		changes[index].Sequence = first + uint(index)
+	}
+	if err := r.db.WithContext(ctx).CreateInBatches(changes, createBatchSize).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (r *GormCustomerRepository) Changes(ctx context.Context, since uint, limit int) ([]core.ChangeRecord, error) {
+	var changes []core.ChangeRecord
+
+	// Get the change records of the tenant after since from database and check Error
+	if err := r.scoped(ctx).Where("sequence > ?", since).Order("sequence").Limit(limit).Find(&changes).Error; err != nil {
+		return []core.ChangeRecord{}, err
+	}
+
+	return changes, nil
+}
+
+func (r *GormCustomerRepository) ChangeHead(ctx context.Context) (uint, error) {
+	var head uint
+
+	// Get the last sequence of the change records of the tenant from database and check Error
+	if err := r.scoped(ctx).Model(&core.ChangeRecord{}).Select("COALESCE(MAX(sequence), 0)").Scan(&head).Error; err != nil {
+		return 0, err
+	}
+
+	return head, nil
+}
+
+func (r *GormCustomerRepository) ExistingNames(ctx context.Context, names []string) ([]string, error) {
+	existing := []string{}
+
+	// Get the names taken in the tenant from database and check Error
+	if err := r.scoped(ctx).Model(&core.Customer{}).Where("name IN ?", names).Pluck("name", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// appendChange inserts the change record of a mutation in tx, the transaction of the mutation
+func appendChange(ctx context.Context, tx *gorm.DB, operation string, customerId uint, customer *core.Customer) error {
+	sequence, err := nextChangeSequences(tx, 1)
+	if err != nil {
+		return err
+	}
+	change := core.NewChangeRecord(ctx, operation, customerId, customer)
+	change.Sequence = sequence
+	return tx.Create(change).Error
+}
+
+// ChangeSequence is the single row counter of the change records. An autoincrement column hands out numbers in
+// the order the inserts start, so a transaction could commit a greater number while a smaller one is still
+// pending and a reader would skip it. Taking numbers updates this row instead, the row stays locked until the
+// transaction ends, so the next writer gets its numbers only after the previous one committed or rolled back
+type ChangeSequence struct {
+	ID    uint `gorm:"primaryKey;autoIncrement:false"`
+	Value uint `gorm:"not null;default:0"`
+}
+
+// changeSequenceId is the id of the only row of the change_sequences table
+const changeSequenceId = 1
+
+// nextChangeSequences takes count numbers of the change log in tx and returns the first of them
+func nextChangeSequences(tx *gorm.DB, count int) (uint, error) {
+	// Update the counter, this locks the row until the end of tx, and check Error
+	result := tx.Model(&ChangeSequence{}).Where("id = ?", changeSequenceId).Update("value", gorm.Expr("value + ?", count))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	// the migration creates the row, it is only missing in databases whose tables were created without it
+	if result.RowsAffected == 0 {
+		if err := tx.Create(&ChangeSequence{ID: changeSequenceId, Value: uint(count)}).Error; err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	// Read the counter back and check Error
+	var sequence ChangeSequence
+	if err := tx.First(&sequence, changeSequenceId).Error; err != nil {
+		return 0, err
+	}
+
+	return sequence.Value - uint(count) + 1, nil
+}
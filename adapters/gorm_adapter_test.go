@@ -1,22 +1,45 @@
 package adapters
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fiatfour/itmx-crud-hex/core"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// Setup an in-memory SQLite database with GORM
+// testModels are the tables of the test database
+var testModels = []interface{}{
+	&core.Customer{}, &core.ChangeRecord{}, &core.AuditEntry{}, &core.OutboxMessage{},
+	&core.WebhookSubscription{}, &core.WebhookDelivery{}, &core.ApiKey{}, &IdempotencyRecord{}, &ChangeSequence{},
+}
+
+// Setup an empty database with GORM, an in-memory SQLite by default. With TEST_POSTGRES_DSN the tests run against
+// that PostgreSQL instead, its tables are dropped and created again for every test:
+//
+//	TEST_POSTGRES_DSN="host=localhost user=postgres dbname=customers_test sslmode=disable" go test -p 1 ./adapters
 func setupTestDB() *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	driver, dsn := DriverSQLite, "file::memory:?cache=shared"
+	if postgresDSN := os.Getenv("TEST_POSTGRES_DSN"); postgresDSN != "" {
+		driver, dsn = DriverPostgres, postgresDSN
+	}
+
+	db, err := OpenDatabase(driver, dsn)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to open database: %v", err))
 	}
-	db.Migrator().CreateTable(&core.Customer{})
+	if driver == DriverPostgres {
+		db.Migrator().DropTable(testModels...)
+	}
+	for _, model := range testModels {
+		db.Migrator().CreateTable(model)
+	}
 	return db
 }
 
@@ -28,7 +51,7 @@ func TestGormCustomerRepository_Save(t *testing.T) {
 		// setup Customer
 		customer := core.Customer{Name: "Fiat", Age: uint(24)}
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(customer)
+		err := repo.Save(context.Background(), &customer)
 		assert.NoError(t, err)
 
 		// Check a row has inserted
@@ -41,7 +64,7 @@ func TestGormCustomerRepository_Save(t *testing.T) {
 		// setup Customer
 		customer := core.Customer{Name: "Fiat", Age: uint(24)}
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(customer)
+		err := repo.Save(context.Background(), &customer)
 		assert.Error(t, err)
 		assert.Equal(t, "name already exists", err.Error())
 	})
@@ -52,7 +75,7 @@ func TestGormCustomerRepository_Save(t *testing.T) {
 		sqlDB.Close()
 
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(core.Customer{Name: "Fiat", Age: 24})
+		err := repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: 24})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "database is closed")
 	})
@@ -64,11 +87,11 @@ func TestGormCustomerRepository_Get(t *testing.T) {
 
 	t.Run("successful get", func(t *testing.T) {
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(core.Customer{Name: "Fiat", Age: 24})
+		err := repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: 24})
 		assert.NoError(t, err)
 
 		// Get() for get a Customer by Id from database and check Value/Error
-		getCustomer, err := repo.Get(1)
+		getCustomer, err := repo.Get(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.Equal(t, "Fiat", getCustomer.Name)
 		assert.Equal(t, uint(24), getCustomer.Age)
@@ -76,7 +99,7 @@ func TestGormCustomerRepository_Get(t *testing.T) {
 
 	t.Run("(fail) customer not found", func(t *testing.T) {
 		// Get() for get a Customer by Id from database and check Value/Error
-		customer, err := repo.Get(uint(999))
+		customer, err := repo.Get(context.Background(), uint(999))
 		assert.Error(t, err)
 		assert.Equal(t, &core.Customer{}, customer)
 		assert.Equal(t, gorm.ErrRecordNotFound, err)
@@ -88,7 +111,7 @@ func TestGormCustomerRepository_Get(t *testing.T) {
 		sqlDB.Close()
 
 		// Get() for get a Customer by Id from database and check Value/Error
-		customer, err := repo.Get(uint(999))
+		customer, err := repo.Get(context.Background(), uint(999))
 		assert.Error(t, err)
 		assert.Equal(t, &core.Customer{}, customer)
 		assert.Contains(t, err.Error(), "database is closed")
@@ -108,12 +131,12 @@ func TestGormCustomerRepository_GetAll(t *testing.T) {
 
 		// Save() loop for insert Customers and check Error
 		for _, customer := range expectedCustomers {
-			err := repo.Save(customer)
+			err := repo.Save(context.Background(), &customer)
 			assert.NoError(t, err)
 		}
 
 		// get all Customers from database and check Value/Error
-		getCustomers, err := repo.GetAll()
+		getCustomers, err := repo.GetAll(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, getCustomers, 2)
 		assert.Equal(t, expectedCustomers[0].Name, getCustomers[0].Name)
@@ -126,7 +149,67 @@ func TestGormCustomerRepository_GetAll(t *testing.T) {
 		sqlDB.Close()
 
 		// get all Customers from database and check Value/Error
-		_, err := repo.GetAll()
+		_, err := repo.GetAll(context.Background())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database is closed")
+	})
+}
+
+func TestGormCustomerRepository_ForEachBatch(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormCustomerRepository(db)
+	bankA := core.WithTenant(context.Background(), "bank-a")
+	for _, name := range []string{"Fiat", "Anfat", "Somchai", "Somsri", "Mana"} {
+		repo.Save(context.Background(), &core.Customer{Name: name, Age: uint(24)})
+	}
+	repo.Save(bankA, &core.Customer{Name: "Other", Age: uint(24)})
+
+	t.Run("successful read in batches", func(t *testing.T) {
+		var batches [][]uint
+		// ForEachBatch() for read all Customers of the tenant 2 at a time and check Error
+		err := repo.ForEachBatch(context.Background(), 2, func(customers []core.Customer) error {
+			ids := make([]uint, len(customers))
+			for index, customer := range customers {
+				ids[index] = customer.ID
+			}
+			batches = append(batches, ids)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]uint{{1, 2}, {3, 4}, {5}}, batches)
+	})
+
+	t.Run("(fail) error of fn stops the iteration", func(t *testing.T) {
+		calls := 0
+		err := repo.ForEachBatch(context.Background(), 2, func(customers []core.Customer) error {
+			calls++
+			return errors.New("client gone")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "client gone", err.Error())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("(fail) canceled between batches", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := repo.ForEachBatch(ctx, 2, func(customers []core.Customer) error {
+			calls++
+			cancel()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("(fail) database error on read", func(t *testing.T) {
+		// Close the database to force an error
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+
+		err := repo.ForEachBatch(context.Background(), 2, func(customers []core.Customer) error {
+			return nil
+		})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "database is closed")
 	})
@@ -146,7 +229,7 @@ func TestGormCustomerRepository_Update(t *testing.T) {
 
 	t.Run("successful update", func(t *testing.T) {
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(customers[0])
+		err := repo.Save(context.Background(), &customers[0])
 		assert.NoError(t, err)
 
 		// Check a row has inserted
@@ -155,7 +238,7 @@ func TestGormCustomerRepository_Update(t *testing.T) {
 		assert.Equal(t, int64(1), count)
 
 		// Update() for update a Customer by Id with Customer[1] in database and check Value/Error
-		updatedCustomer, err := repo.Update(uint(1), &customers[1])
+		updatedCustomer, err := repo.Update(context.Background(), uint(1), &customers[1])
 		assert.NoError(t, err)
 		assert.Equal(t, uint(1), updatedCustomer.ID)
 		assert.Equal(t, &customers[1].Name, &updatedCustomer.Name)
@@ -164,11 +247,11 @@ func TestGormCustomerRepository_Update(t *testing.T) {
 
 	t.Run("(fail) name already exists error", func(t *testing.T) {
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(customers[2])
+		err := repo.Save(context.Background(), &customers[2])
 		assert.NoError(t, err)
 
 		// Update() for update a Customer by Id with Customer[1] in database and check Value/Error
-		updatedCustomer, err := repo.Update(uint(1), &customers[2])
+		updatedCustomer, err := repo.Update(context.Background(), uint(1), &customers[2])
 		assert.Equal(t, &core.Customer{}, updatedCustomer)
 		assert.Error(t, err)
 		assert.Equal(t, "name already exists", err.Error())
@@ -180,7 +263,7 @@ func TestGormCustomerRepository_Update(t *testing.T) {
 		sqlDB.Close()
 
 		// Update() for update a Customer by Id with Customer[3] in database and check Value/Error
-		updatedCustomer, err := repo.Update(uint(1), &customers[3])
+		updatedCustomer, err := repo.Update(context.Background(), uint(1), &customers[3])
 		assert.Error(t, err)
 		assert.Equal(t, &core.Customer{}, updatedCustomer)
 		assert.Contains(t, err.Error(), "database is closed")
@@ -193,7 +276,7 @@ func TestGormCustomerRepository_Delete(t *testing.T) {
 
 	t.Run("successful delete", func(t *testing.T) {
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(core.Customer{Name: "Fiat", Age: uint(24)})
+		err := repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)})
 		assert.NoError(t, err)
 
 		// Check a row has inserted
@@ -202,8 +285,16 @@ func TestGormCustomerRepository_Delete(t *testing.T) {
 		assert.Equal(t, int64(1), count)
 
 		// Delete() for delete a Customer by Id in database and check Error
-		err = repo.Delete(uint(999))
+		err = repo.Delete(context.Background(), uint(1))
 		assert.NoError(t, err)
+		db.Model(&core.Customer{}).Where("name = ?", "Fiat").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("(fail) missing customer", func(t *testing.T) {
+		// Delete() for delete a missing Customer and check Error
+		err := repo.Delete(context.Background(), uint(999))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 	})
 
 	t.Run("(fail) database error on delete", func(t *testing.T) {
@@ -212,7 +303,7 @@ func TestGormCustomerRepository_Delete(t *testing.T) {
 		sqlDB.Close()
 
 		// Delete() for delete a Customer by Id in database and check Error
-		err := repo.Delete(uint(1))
+		err := repo.Delete(context.Background(), uint(1))
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "database is closed")
 	})
@@ -224,7 +315,7 @@ func TestGormCustomerRepository_Search(t *testing.T) {
 
 	t.Run("successful search", func(t *testing.T) {
 		// Save() for insert a Customer in database and check Error
-		err := repo.Save(core.Customer{Name: "Fiat", Age: uint(24)})
+		err := repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)})
 		assert.NoError(t, err)
 
 		// Check a row has inserted
@@ -233,13 +324,13 @@ func TestGormCustomerRepository_Search(t *testing.T) {
 		assert.Equal(t, int64(1), count)
 
 		// Search() for search a Customer by Id from database and check Error
-		err = repo.Search(uint(1))
+		err = repo.Search(context.Background(), uint(1))
 		assert.NoError(t, err)
 	})
 
 	t.Run("(fail) not found on search", func(t *testing.T) {
 		// Search() for search a Customer by Id from database and check Error
-		err := repo.Search(uint(999))
+		err := repo.Search(context.Background(), uint(999))
 		assert.Error(t, err)
 	})
 
@@ -249,8 +340,310 @@ func TestGormCustomerRepository_Search(t *testing.T) {
 		sqlDB.Close()
 
 		// Search() for search a Customer by Id from database and check Value/Error
-		err := repo.Search(uint(1))
+		err := repo.Search(context.Background(), uint(1))
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "database is closed")
 	})
 }
+
+func TestGormCustomerRepository_ExistingNames(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormCustomerRepository(db)
+
+	t.Run("successful names of the tenant", func(t *testing.T) {
+		assert.NoError(t, repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)}))
+		assert.NoError(t, repo.Save(core.WithTenant(context.Background(), "bank-b"), &core.Customer{Name: "Anfat", Age: uint(30)}))
+
+		// ExistingNames() for get the names taken in the tenant from database and check Error
+		existing, err := repo.ExistingNames(context.Background(), []string{"Fiat", "Anfat", "Somchai"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Fiat"}, existing)
+	})
+
+	t.Run("(fail) database error on existing names", func(t *testing.T) {
+		// Close the database to force an error
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+
+		_, err := repo.ExistingNames(context.Background(), []string{"Fiat"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database is closed")
+	})
+}
+
+func TestGormCustomerRepository_TenantIsolation(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormCustomerRepository(db)
+	bankA := core.WithPrincipal(context.Background(), &core.Principal{Subject: "user-a", TenantID: "bank-a"})
+	bankB := core.WithPrincipal(context.Background(), &core.Principal{Subject: "user-b", TenantID: "bank-b"})
+
+	// Save() the same name in both tenants, names are only unique per tenant
+	assert.NoError(t, repo.Save(bankA, &core.Customer{Name: "Fiat", Age: uint(24)}))
+	assert.NoError(t, repo.Save(bankB, &core.Customer{Name: "Fiat", Age: uint(40)}))
+	assert.Error(t, repo.Save(bankA, &core.Customer{Name: "Fiat", Age: uint(30)}))
+
+	t.Run("(fail) cross-tenant read", func(t *testing.T) {
+		// customer 1 belongs to bank-a
+		_, err := repo.Get(bankB, uint(1))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.Search(bankB, uint(1)), gorm.ErrRecordNotFound)
+
+		customers, err := repo.GetAll(bankB)
+		assert.NoError(t, err)
+		assert.Len(t, customers, 1)
+		assert.Equal(t, uint(2), customers[0].ID)
+	})
+
+	t.Run("(fail) cross-tenant update", func(t *testing.T) {
+		_, err := repo.Update(bankB, uint(1), &core.Customer{Name: "Anfat", Age: uint(99)})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// customer 1 of bank-a is unchanged
+		customer, err := repo.Get(bankA, uint(1))
+		assert.NoError(t, err)
+		assert.Equal(t, "Fiat", customer.Name)
+		assert.Equal(t, uint(24), customer.Age)
+	})
+
+	t.Run("(fail) cross-tenant delete", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Delete(bankB, uint(1)), gorm.ErrRecordNotFound)
+
+		// customer 1 of bank-a still exists
+		assert.NoError(t, repo.Search(bankA, uint(1)))
+	})
+
+	t.Run("(fail) tenant can not be moved by an update", func(t *testing.T) {
+		_, err := repo.Update(bankB, uint(2), &core.Customer{TenantID: "bank-a", Name: "Anfat", Age: uint(41)})
+		assert.NoError(t, err)
+
+		customer, err := repo.Get(bankB, uint(2))
+		assert.NoError(t, err)
+		assert.Equal(t, "bank-b", customer.TenantID)
+	})
+
+	t.Run("successful in own tenant", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(bankA, uint(1)))
+		assert.ErrorIs(t, repo.Search(bankA, uint(1)), gorm.ErrRecordNotFound)
+	})
+
+	// Close the database so other tests start empty
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+}
+
+func TestGormCustomerRepository_ApplyBatch(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormCustomerRepository(db)
+	assert.NoError(t, repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)}))
+
+	t.Run("successful batch", func(t *testing.T) {
+		// ApplyBatch() creates, updates and deletes in one transaction and check Error
+		ids, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Anfat", Age: uint(30)}},
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Bank", Age: uint(40)}},
+			{Operation: core.BatchOperationUpdate, ID: uint(1), Customer: core.Customer{Name: "Fiat", Age: uint(25)}},
+			{Operation: core.BatchOperationDelete, ID: uint(3)},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{2, 3, 1, 3}, ids)
+
+		customers, err := repo.GetAll(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []core.Customer{{ID: 1, Name: "Fiat", Age: 25}, {ID: 2, Name: "Anfat", Age: 30}}, customers)
+	})
+
+	t.Run("(fail) name already exists rolls back the batch", func(t *testing.T) {
+		_, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationUpdate, ID: uint(1), Customer: core.Customer{Name: "Fiat", Age: uint(99)}},
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Somchai", Age: uint(30)}},
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Anfat", Age: uint(30)}},
+		})
+		var itemErr *core.BatchItemError
+		assert.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 2, itemErr.Index)
+		assert.ErrorIs(t, err, core.ErrNameAlreadyExists)
+
+		// nothing of the batch was applied
+		customer, _ := repo.Get(context.Background(), uint(1))
+		assert.Equal(t, uint(25), customer.Age)
+		var count int64
+		db.Model(&core.Customer{}).Where("name = ?", "Somchai").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("(fail) duplicate name inside the batch", func(t *testing.T) {
+		_, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Somchai", Age: uint(30)}},
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Somchai", Age: uint(31)}},
+		})
+		var itemErr *core.BatchItemError
+		assert.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 1, itemErr.Index)
+	})
+
+	t.Run("(fail) update of missing customer", func(t *testing.T) {
+		_, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationUpdate, ID: uint(99), Customer: core.Customer{Name: "Nobody", Age: uint(30)}},
+		})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("(fail) delete of missing customer", func(t *testing.T) {
+		// the customer of another tenant is missing for the default tenant too
+		assert.NoError(t, repo.Save(core.WithTenant(context.Background(), "bank-b"), &core.Customer{Name: "Other", Age: uint(30)}))
+		var other core.Customer
+		db.Where("name = ?", "Other").First(&other)
+		var before int64
+		db.Model(&core.ChangeRecord{}).Count(&before)
+
+		for _, customerId := range []uint{uint(99), other.ID} {
+			_, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+				{Operation: core.BatchOperationDelete, ID: customerId},
+			})
+			var itemErr *core.BatchItemError
+			assert.ErrorAs(t, err, &itemErr)
+			assert.Equal(t, 0, itemErr.Index)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		}
+
+		// no tombstone was written
+		var after int64
+		db.Model(&core.ChangeRecord{}).Count(&after)
+		assert.Equal(t, before, after)
+	})
+
+	// Close the database so other tests start empty
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+}
+func TestGormCustomerRepository_Changes(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormCustomerRepository(db)
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	bankA := core.WithTenant(context.Background(), "bank-a")
+
+	// every mutation writes a change record
+	assert.NoError(t, repo.Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)}))
+	assert.NoError(t, repo.Save(bankA, &core.Customer{Name: "Anfat", Age: uint(40)}))
+	_, err := repo.Update(context.Background(), uint(1), &core.Customer{Age: uint(25)})
+	assert.NoError(t, err)
+	_, err = repo.ApplyBatch(context.Background(), []core.BatchOperation{
+		{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Bank", Age: uint(30)}},
+		{Operation: core.BatchOperationDelete, ID: uint(1)},
+	})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, repo.Delete(context.Background(), uint(999)), gorm.ErrRecordNotFound)
+
+	t.Run("successful changes in order with tombstone", func(t *testing.T) {
+		changes, err := repo.Changes(context.Background(), 0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 4)
+
+		var sequences []uint
+		var operations []string
+		for _, change := range changes {
+			sequences = append(sequences, change.Sequence)
+			operations = append(operations, change.Operation)
+		}
+		assert.Equal(t, []uint{1, 3, 4, 5}, sequences)
+		assert.Equal(t, []string{core.ChangeCreate, core.ChangeUpdate, core.ChangeCreate, core.ChangeDelete}, operations)
+
+		// the update keeps the whole customer, the delete is a tombstone
+		assert.Equal(t, &core.Customer{ID: 1, Name: "Fiat", Age: 25}, changes[1].Customer)
+		assert.Equal(t, uint(1), changes[3].CustomerID)
+		assert.True(t, changes[3].Deleted)
+		assert.Nil(t, changes[3].Customer)
+	})
+
+	t.Run("successful page after since of the tenant", func(t *testing.T) {
+		changes, err := repo.Changes(context.Background(), 3, 1)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 1)
+		assert.Equal(t, uint(4), changes[0].Sequence)
+
+		changes, err = repo.Changes(bankA, 0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 1)
+		assert.Equal(t, "Anfat", changes[0].Customer.Name)
+	})
+
+	t.Run("successful head of the tenant", func(t *testing.T) {
+		head, err := repo.ChangeHead(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), head)
+
+		head, _ = repo.ChangeHead(bankA)
+		assert.Equal(t, uint(2), head)
+		head, _ = repo.ChangeHead(core.WithTenant(context.Background(), "bank-b"))
+		assert.Equal(t, uint(0), head)
+	})
+
+	t.Run("(fail) rolled back batch leaves no change", func(t *testing.T) {
+		_, err := repo.ApplyBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Somchai", Age: uint(30)}},
+			{Operation: core.BatchOperationUpdate, ID: uint(999), Customer: core.Customer{Age: uint(30)}},
+		})
+		assert.Error(t, err)
+
+		changes, _ := repo.Changes(context.Background(), 5, 10)
+		assert.Empty(t, changes)
+	})
+}
+
+func TestGormCustomerRepository_ChangesCommitOrder(t *testing.T) {
+	// sqlite has one writer at a time, its transactions wait for each other with a busy timeout instead of failing.
+	// On postgres (TEST_POSTGRES_DSN) both transactions write and the second one waits on the change sequence
+	db := setupTestDB()
+	if os.Getenv("TEST_POSTGRES_DSN") == "" {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+		var err error
+		db, err = OpenDatabase(DriverSQLite, filepath.Join(t.TempDir(), "changes.db")+"?_txlock=immediate&_busy_timeout=5000")
+		assert.NoError(t, err)
+		for _, model := range testModels {
+			db.Migrator().CreateTable(model)
+		}
+	}
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	repo := NewGormCustomerRepository(db)
+
+	t.Run("successful changes visible in sequence order", func(t *testing.T) {
+		// the first transaction takes its sequence and stays open
+		tx := db.Begin()
+		assert.NoError(t, NewGormCustomerRepository(tx).Save(context.Background(), &core.Customer{Name: "Fiat", Age: uint(24)}))
+
+		// the second transaction starts later and can not commit before the first one
+		done := make(chan error, 1)
+		go func() {
+			done <- repo.Save(context.Background(), &core.Customer{Name: "Anfat", Age: uint(30)})
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("second transaction committed before the first one: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		changes, err := repo.Changes(context.Background(), 0, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+
+		assert.NoError(t, tx.Commit().Error)
+		assert.NoError(t, <-done)
+
+		// a reader that saw a change has seen every change before it
+		changes, err = repo.Changes(context.Background(), 0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, uint(1), changes[0].Sequence)
+		assert.Equal(t, "Fiat", changes[0].Customer.Name)
+		assert.Equal(t, uint(2), changes[1].Sequence)
+		assert.Equal(t, "Anfat", changes[1].Customer.Name)
+	})
+}
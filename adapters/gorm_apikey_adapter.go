@@ -0,0 +1,84 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_apikey_adapter.go)
+
+type GormApiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormApiKeyRepository(db *gorm.DB) core.ApiKeyRepository {
+	return &GormApiKeyRepository{db: db}
+}
+
+// scoped starts the queries of one tenant
+func (r *GormApiKeyRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Where("tenant_id = ?", core.TenantFromContext(ctx))
+}
+
+func (r *GormApiKeyRepository) Save(ctx context.Context, apiKey *core.ApiKey) error {
+	// Insert ApiKey in database and check Error
+	if err := r.db.WithContext(ctx).Create(apiKey).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormApiKeyRepository) Get(ctx context.Context, apiKeyId uint) (*core.ApiKey, error) {
+	var apiKey core.ApiKey
+
+	// Get an ApiKey of the tenant from database and check Error
+	if err := r.scoped(ctx).First(&apiKey, apiKeyId).Error; err != nil {
+		return &core.ApiKey{}, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *GormApiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*core.ApiKey, error) {
+	var apiKey core.ApiKey
+
+	// Get an ApiKey by its prefix from database and check Error
+	if err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&apiKey).Error; err != nil {
+		return &core.ApiKey{}, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *GormApiKeyRepository) GetAll(ctx context.Context) ([]core.ApiKey, error) {
+	var apiKeys []core.ApiKey
+
+	// Get all ApiKeys of the tenant from database and check Error
+	if err := r.scoped(ctx).Find(&apiKeys).Error; err != nil {
+		return []core.ApiKey{}, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *GormApiKeyRepository) Update(ctx context.Context, apiKey *core.ApiKey) error {
+	// Save every column so cleared fields (e.g. LastUsedAt after rotation) are written too and check Error
+	if err := r.db.WithContext(ctx).Save(apiKey).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormApiKeyRepository) TouchLastUsed(ctx context.Context, apiKeyId uint, usedAt time.Time) error {
+	// Update only last_used_at of the ApiKey and check Error
+	if err := r.db.WithContext(ctx).Model(&core.ApiKey{}).Where("id = ?", apiKeyId).Update("last_used_at", usedAt).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
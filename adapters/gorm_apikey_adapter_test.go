@@ -0,0 +1,80 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestGormApiKeyRepository(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormApiKeyRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful save and get by prefix", func(t *testing.T) {
+		// Save() for insert an ApiKey in database and check Error
+		apiKey := &core.ApiKey{Name: "batch partner", Prefix: "abc123", SecretHash: "hash", Scopes: []string{"customers:read"}}
+		err := repo.Save(ctx, apiKey)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), apiKey.ID)
+
+		// GetByPrefix() for get the ApiKey back with its scopes and check Value/Error
+		found, err := repo.GetByPrefix(ctx, "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "batch partner", found.Name)
+		assert.Equal(t, []string{"customers:read"}, found.Scopes)
+	})
+
+	t.Run("successful update and touch last used", func(t *testing.T) {
+		apiKey, err := repo.Get(ctx, 1)
+		assert.NoError(t, err)
+
+		// Update() for revoke the ApiKey and check Error
+		revokedAt := time.Now()
+		apiKey.RevokedAt = &revokedAt
+		assert.NoError(t, repo.Update(ctx, apiKey))
+
+		// TouchLastUsed() for set the last use of the ApiKey and check Error
+		assert.NoError(t, repo.TouchLastUsed(ctx, 1, time.Now()))
+
+		apiKeys, err := repo.GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, apiKeys, 1)
+		assert.NotNil(t, apiKeys[0].RevokedAt)
+		assert.NotNil(t, apiKeys[0].LastUsedAt)
+	})
+
+	t.Run("(fail) key of another tenant", func(t *testing.T) {
+		otherTenant := core.WithTenant(ctx, "bank-b")
+		_, err := repo.Get(otherTenant, 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		apiKeys, err := repo.GetAll(otherTenant)
+		assert.NoError(t, err)
+		assert.Empty(t, apiKeys)
+	})
+
+	t.Run("(fail) prefix not found", func(t *testing.T) {
+		_, err := repo.GetByPrefix(ctx, "missing")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("(fail) duplicate prefix", func(t *testing.T) {
+		err := repo.Save(ctx, &core.ApiKey{Name: "other", Prefix: "abc123", SecretHash: "hash"})
+		assert.Error(t, err)
+	})
+
+	t.Run("(fail) database error", func(t *testing.T) {
+		// Close the database to force an error
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+
+		_, err := repo.GetAll(ctx)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database is closed")
+	})
+}
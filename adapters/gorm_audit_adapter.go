@@ -0,0 +1,28 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_audit_adapter.go)
+
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) core.AuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+func (r *GormAuditRepository) Append(ctx context.Context, entry *core.AuditEntry) error {
+	// Insert the AuditEntry into the tenant of ctx in database and check Error
+	entry.TenantID = core.TenantFromContext(ctx)
+	if err := r.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
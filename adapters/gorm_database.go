@@ -0,0 +1,71 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_database.go)
+
+// Drivers of OpenDatabase, the dsn of sqlite is the path of the database file and the one of postgres is a
+// connection string like "host=localhost user=app dbname=customers sslmode=disable" or a postgres:// url
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+var ErrUnknownDatabaseDriver = errors.New("database driver must be sqlite or postgres")
+
+// pgUniqueViolation is the SQLSTATE of postgres for a violated unique constraint
+const pgUniqueViolation = "23505"
+
+// OpenDatabase opens the database of driver at dsn, no driver means sqlite
+func OpenDatabase(driver string, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case "", DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("%w, not %q", ErrUnknownDatabaseDriver, driver)
+	}
+
+	return gorm.Open(dialector, &gorm.Config{})
+}
+
+// isUniqueViolation reports whether err is a violated unique constraint in the error of any dialect, the unique
+// index catches the duplicates that two requests insert at the same time after both checked the name is free
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+
+	return false
+}
+
+// translateCustomerError turns a violated unique index of the customers, the only one on their name in a tenant,
+// into core.ErrNameAlreadyExists, other errors are returned as they are
+func translateCustomerError(err error) error {
+	if isUniqueViolation(err) {
+		return core.ErrNameAlreadyExists
+	}
+	return err
+}
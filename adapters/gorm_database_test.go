@@ -0,0 +1,52 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenDatabase(t *testing.T) {
+	// Failure case
+	t.Run("(fail) unknown driver", func(t *testing.T) {
+		db, err := OpenDatabase("mysql", "customers")
+		assert.Nil(t, db)
+		assert.ErrorIs(t, err, ErrUnknownDatabaseDriver)
+		assert.Equal(t, `database driver must be sqlite or postgres, not "mysql"`, err.Error())
+	})
+}
+
+func TestTranslateCustomerError(t *testing.T) {
+	// Success case
+	t.Run("successful unique violation of the database", func(t *testing.T) {
+		db := setupTestDB()
+		defer func() {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		}()
+
+		// insert the same name twice without the check of the repository, the unique index rejects it
+		assert.NoError(t, db.Create(&core.Customer{Name: "Fiat", Age: uint(24)}).Error)
+		err := db.Create(&core.Customer{Name: "Fiat", Age: uint(40)}).Error
+		assert.Error(t, err)
+		assert.Equal(t, core.ErrNameAlreadyExists, translateCustomerError(err))
+	})
+
+	t.Run("successful unique violation of postgres", func(t *testing.T) {
+		err := fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "idx_customers_tenant_name"})
+		assert.Equal(t, core.ErrNameAlreadyExists, translateCustomerError(err))
+	})
+
+	// Failure case
+	t.Run("(fail) other errors are kept", func(t *testing.T) {
+		err := &pgconn.PgError{Code: "23502"}
+		assert.Equal(t, err, translateCustomerError(err))
+
+		err2 := errors.New("database is closed")
+		assert.Equal(t, err2, translateCustomerError(err2))
+	})
+}
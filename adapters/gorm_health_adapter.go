@@ -0,0 +1,28 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_health_adapter.go)
+
+type GormHealthRepository struct {
+	db *gorm.DB
+}
+
+func NewGormHealthRepository(db *gorm.DB) core.HealthRepository {
+	return &GormHealthRepository{db: db}
+}
+
+func (r *GormHealthRepository) Ping(ctx context.Context) error {
+	// Get the connection pool of gorm and ping the database
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,86 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// * Secondary adapter (gorm_idempotency_adapter.go)
+
+// GormIdempotencyStore keeps the records in the database, so retries reaching another instance are replayed too
+type GormIdempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewGormIdempotencyStore(db *gorm.DB) IdempotencyStore {
+	return &GormIdempotencyStore{db: db}
+}
+
+func (s *GormIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string, expiresAt time.Time) (*IdempotencyRecord, bool, error) {
+	db := s.db.WithContext(ctx)
+
+	// Remove an expired record of the key, so the key can be reserved again, and check Error
+	if err := db.Where("idempotency_key = ? AND expires_at <= ?", key, time.Now()).Delete(&IdempotencyRecord{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	// Insert the reservation unless the key exists, the primary key makes this atomic, and check Error
+	record := &IdempotencyRecord{Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	// Get the record of the first request and check Error
+	var existing IdempotencyRecord
+	if err := db.Where("idempotency_key = ?", key).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+
+	return &existing, false, nil
+}
+
+func (s *GormIdempotencyStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	// Update the record with the response and check Error
+	result := s.db.WithContext(ctx).Model(&IdempotencyRecord{}).Where("idempotency_key = ?", key).Updates(map[string]interface{}{
+		"completed":    true,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"body":         body,
+		"expires_at":   expiresAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("idempotency key not reserved")
+	}
+
+	return nil
+}
+
+func (s *GormIdempotencyStore) Release(ctx context.Context, key string) error {
+	// Delete the reservation and check Error
+	if err := s.db.WithContext(ctx).Where("idempotency_key = ?", key).Delete(&IdempotencyRecord{}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *GormIdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	// Delete every expired record and check Error
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&IdempotencyRecord{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
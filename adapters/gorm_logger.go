@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// * Secondary adapter (gorm_logger.go)
+
+// GormLogger writes the logs of gorm with the logger of the context (see core.LoggerFromContext), so the SQL
+// statements of a request carry its request id. Statements are logged at debug, slow ones as warning and failed
+// ones as error. The values of the statements are never logged, they hold customer data
+type GormLogger struct {
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{slowThreshold: slowThreshold, level: logger.Info}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		core.LoggerFromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		core.LoggerFromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		core.LoggerFromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	// choose the level first, so the SQL is only rendered when the logger writes it
+	var level slog.Level
+	var msg string
+	var extra []slog.Attr
+	switch {
+	// a missing row is an answer of the database, not a failed statement
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		level, msg, extra = slog.LevelError, "query failed", []slog.Attr{slog.String("error", err.Error())}
+	case elapsed > l.slowThreshold && l.level >= logger.Warn:
+		level, msg, extra = slog.LevelWarn, "slow query", []slog.Attr{slog.Duration("threshold", l.slowThreshold)}
+	case l.level >= logger.Info:
+		level, msg = slog.LevelDebug, "query"
+	default:
+		return
+	}
+	log := core.LoggerFromContext(ctx)
+	if !log.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	log.LogAttrs(ctx, level, msg, append(attrs, extra...)...)
+}
+
+// ParamsFilter keeps the placeholders of the statements instead of their values
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
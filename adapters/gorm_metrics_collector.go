@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_metrics_collector.go)
+
+// customerCountTimeout bounds the count query of one scrape
+const customerCountTimeout = 5 * time.Second
+
+// CustomerCountCollector is the business gauge of the customers of every tenant, counted at each scrape
+type CustomerCountCollector struct {
+	db     *gorm.DB
+	total  *prometheus.Desc
+	failed *prometheus.Desc
+}
+
+func NewCustomerCountCollector(db *gorm.DB) *CustomerCountCollector {
+	return &CustomerCountCollector{
+		db:     db,
+		total:  prometheus.NewDesc("customers_total", "Customers stored per tenant.", []string{"tenant"}, nil),
+		failed: prometheus.NewDesc("customers_count_failed", "1 when the customers could not be counted at this scrape.", nil, nil),
+	}
+}
+
+func (c *CustomerCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.failed
+}
+
+func (c *CustomerCountCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), customerCountTimeout)
+	defer cancel()
+
+	// Count the customers of every tenant in database and check Error
+	var counts []struct {
+		TenantID string
+		Count    int64
+	}
+	err := c.db.WithContext(ctx).Model(&core.Customer{}).Select("tenant_id, count(*) AS count").Group("tenant_id").Scan(&counts).Error
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.GaugeValue, 1)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.failed, prometheus.GaugeValue, 0)
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(count.Count), count.TenantID)
+	}
+}
@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_outbox_adapter.go)
+
+type GormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOutboxRepository(db *gorm.DB) core.OutboxRepository {
+	return &GormOutboxRepository{db: db}
+}
+
+func (r *GormOutboxRepository) Append(ctx context.Context, message *core.OutboxMessage) error {
+	// Insert the OutboxMessage into the tenant of ctx in database and check Error
+	message.TenantID = core.TenantFromContext(ctx)
+	if err := r.db.WithContext(ctx).Create(message).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormOutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]core.OutboxMessage, error) {
+	var messages []core.OutboxMessage
+
+	// Select the due messages and push their next attempt behind the lease in one transaction and check Error,
+	// the update only claims the rows no other relay claimed in the meantime
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []core.OutboxMessage
+		if err := tx.Where("published_at IS NULL AND next_attempt_at <= ?", now).Order("id").Limit(limit).Find(&due).Error; err != nil {
+			return err
+		}
+
+		for _, message := range due {
+			result := tx.Model(&core.OutboxMessage{}).
+				Where("id = ? AND published_at IS NULL AND next_attempt_at = ?", message.ID, message.NextAttemptAt).
+				Update("next_attempt_at", now.Add(lease))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				messages = append(messages, message)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []core.OutboxMessage{}, err
+	}
+
+	return messages, nil
+}
+
+func (r *GormOutboxRepository) MarkPublished(ctx context.Context, messageId uint, publishedAt time.Time) error {
+	// Update the message as published and check Error
+	if err := r.db.WithContext(ctx).Model(&core.OutboxMessage{}).Where("id = ?", messageId).Update("published_at", publishedAt).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormOutboxRepository) MarkFailed(ctx context.Context, messageId uint, nextAttemptAt time.Time, lastError string) error {
+	// Count the attempt and set the next one and check Error
+	err := r.db.WithContext(ctx).Model(&core.OutboxMessage{}).Where("id = ?", messageId).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGormOutboxRepository(t *testing.T) {
+	db := setupTestDB()
+	repo := NewGormOutboxRepository(db)
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bankA := core.WithTenant(context.Background(), "bank-a")
+
+	// Append() messages of two tenants, the third one is not due yet
+	assert.NoError(t, repo.Append(context.Background(), &core.OutboxMessage{EventType: core.EventCustomerCreated, CustomerID: 1, Payload: []byte("{}"), OccurredAt: now, NextAttemptAt: now}))
+	assert.NoError(t, repo.Append(bankA, &core.OutboxMessage{EventType: core.EventCustomerCreated, CustomerID: 2, Payload: []byte("{}"), OccurredAt: now, NextAttemptAt: now}))
+	assert.NoError(t, repo.Append(context.Background(), &core.OutboxMessage{EventType: core.EventCustomerDeleted, CustomerID: 1, Payload: []byte("{}"), OccurredAt: now, NextAttemptAt: now.Add(time.Hour)}))
+
+	t.Run("successful claim of due messages of every tenant", func(t *testing.T) {
+		messages, err := repo.ClaimPending(context.Background(), now, time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, messages, 2)
+		assert.Equal(t, uint(1), messages[0].ID)
+		assert.Equal(t, "bank-a", messages[1].TenantID)
+
+		// claimed messages are hidden until the lease ends
+		messages, _ = repo.ClaimPending(context.Background(), now, time.Minute, 10)
+		assert.Empty(t, messages)
+		messages, _ = repo.ClaimPending(context.Background(), now.Add(time.Minute), time.Minute, 10)
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("successful mark published and failed", func(t *testing.T) {
+		assert.NoError(t, repo.MarkPublished(context.Background(), 1, now))
+		assert.NoError(t, repo.MarkFailed(context.Background(), 2, now.Add(2*time.Hour), "broker unavailable"))
+
+		messages, err := repo.ClaimPending(context.Background(), now.Add(90*time.Minute), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, uint(3), messages[0].ID)
+
+		// the lease of message 3 is over too
+		messages, _ = repo.ClaimPending(context.Background(), now.Add(3*time.Hour), time.Minute, 10)
+		assert.Len(t, messages, 2)
+		assert.Equal(t, uint(2), messages[0].ID)
+		assert.Equal(t, 1, messages[0].Attempts)
+		assert.Equal(t, "broker unavailable", messages[0].LastError)
+	})
+}
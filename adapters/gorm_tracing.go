@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_tracing.go)
+
+// gormParentContextKey keeps the context of the statement before its span started
+const gormParentContextKey = "tracing:parent_context"
+
+// GormTracing is a gorm plugin that starts a span around every SQL statement, as child of the span in the context
+// of the query (db.WithContext). The statement is recorded with its placeholders, never with its values
+type GormTracing struct {
+	tracer trace.Tracer
+}
+
+func NewGormTracing(tp trace.TracerProvider) *GormTracing {
+	return &GormTracing{tracer: tp.Tracer(tracerName)}
+}
+
+func (p *GormTracing) Name() string {
+	return "tracing"
+}
+
+func (p *GormTracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	// Register the span around the main callback of every processor and check Error
+	for _, register := range []struct {
+		operation string
+		before    func(name string, fn func(db *gorm.DB)) error
+		after     func(name string, fn func(db *gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	} {
+		if err := register.before("tracing:before_"+register.operation, p.before(register.operation)); err != nil {
+			return err
+		}
+		if err := register.after("tracing:after_"+register.operation, p.after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *GormTracing) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, _ := p.tracer.Start(db.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name()), semconv.DBCollectionName(db.Statement.Table)))
+		db.InstanceSet(gormParentContextKey, db.Statement.Context)
+		db.Statement.Context = ctx
+	}
+}
+
+func (p *GormTracing) after(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()))
+
+	// a missing row is an answer of the database, not a failed statement
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	endSpan(span, err)
+
+	// the next statement of the same session starts again from the parent
+	if parent, ok := db.InstanceGet(gormParentContextKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+}
@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (gorm_unit_of_work.go)
+
+// gormTxKey holds the running transaction in the ctx given to the callback, so nested calls can join it
+type gormTxKey struct{}
+
+// CustomerRepositoryDecorator wraps a CustomerRepository, e.g. with metrics
+type CustomerRepositoryDecorator func(r core.CustomerRepository) core.CustomerRepository
+
+type GormUnitOfWork struct {
+	db         *gorm.DB
+	decorators []CustomerRepositoryDecorator
+}
+
+// NewGormUnitOfWork applies the decorators in order to the customer repository of every transaction, so the writes
+// are decorated like the reads
+func NewGormUnitOfWork(db *gorm.DB, decorators ...CustomerRepositoryDecorator) core.UnitOfWork {
+	return &GormUnitOfWork{db: db, decorators: decorators}
+}
+
+func (u *GormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos core.Repositories) error) error {
+	// join the transaction of an outer call, gorm runs a transaction inside another one in a savepoint
+	db := u.db
+	if tx, ok := ctx.Value(gormTxKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+
+	// Run fn in a transaction, gorm rolls back on error and on panic, and check Error
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		customers := NewGormCustomerRepository(tx)
+		for _, decorate := range u.decorators {
+			customers = decorate(customers)
+		}
+		return fn(context.WithValue(ctx, gormTxKey{}, tx), core.Repositories{
+			Customers: customers,
+			Audit:     NewGormAuditRepository(tx),
+			Outbox:    NewGormOutboxRepository(tx),
+		})
+	})
+}
@@ -0,0 +1,129 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestGormUnitOfWork(t *testing.T) {
+	db := setupTestDB()
+	uow := NewGormUnitOfWork(db)
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	count := func(model interface{}) int64 {
+		var count int64
+		db.Model(model).Count(&count)
+		return count
+	}
+	createWithAudit := func(ctx context.Context, repos core.Repositories, name string) error {
+		customer := core.Customer{Name: name, Age: uint(24)}
+		if err := repos.Customers.Save(ctx, &customer); err != nil {
+			return err
+		}
+		return repos.Audit.Append(ctx, &core.AuditEntry{Action: core.AuditActionCustomerCreated, CustomerID: customer.ID})
+	}
+
+	// Success case
+	t.Run("successful commit", func(t *testing.T) {
+		// Do() for create a customer and its audit entry and check Error
+		err := uow.Do(context.Background(), func(ctx context.Context, repos core.Repositories) error {
+			return createWithAudit(ctx, repos, "Fiat")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count(&core.Customer{}))
+		assert.Equal(t, int64(1), count(&core.AuditEntry{}))
+	})
+
+	t.Run("successful nested call rolled back on its own", func(t *testing.T) {
+		err := uow.Do(context.Background(), func(ctx context.Context, repos core.Repositories) error {
+			if err := createWithAudit(ctx, repos, "Anfat"); err != nil {
+				return err
+			}
+
+			// the nested call fails and its error is handled, only its savepoint is rolled back
+			nestedErr := uow.Do(ctx, func(ctx context.Context, repos core.Repositories) error {
+				if err := createWithAudit(ctx, repos, "Somchai"); err != nil {
+					return err
+				}
+				return errors.New("nested error")
+			})
+			assert.Error(t, nestedErr)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count(&core.Customer{}))
+		assert.Equal(t, int64(2), count(&core.AuditEntry{}))
+	})
+
+	// Failure case
+	t.Run("(fail) error rolls back every repository", func(t *testing.T) {
+		err := uow.Do(context.Background(), func(ctx context.Context, repos core.Repositories) error {
+			if err := createWithAudit(ctx, repos, "Somsri"); err != nil {
+				return err
+			}
+			// the name exists, so the second create fails
+			return createWithAudit(ctx, repos, "Fiat")
+		})
+		assert.ErrorIs(t, err, core.ErrNameAlreadyExists)
+		assert.Equal(t, int64(2), count(&core.Customer{}))
+		assert.Equal(t, int64(2), count(&core.AuditEntry{}))
+	})
+
+	t.Run("(fail) panic rolls back and is passed on", func(t *testing.T) {
+		assert.PanicsWithValue(t, "boom", func() {
+			uow.Do(context.Background(), func(ctx context.Context, repos core.Repositories) error {
+				createWithAudit(ctx, repos, "Mana")
+				panic("boom")
+			})
+		})
+		assert.Equal(t, int64(2), count(&core.Customer{}))
+		assert.Equal(t, int64(2), count(&core.AuditEntry{}))
+	})
+
+	t.Run("(fail) nested error returned by the outer call rolls back everything", func(t *testing.T) {
+		err := uow.Do(context.Background(), func(ctx context.Context, repos core.Repositories) error {
+			if err := createWithAudit(ctx, repos, "Mana"); err != nil {
+				return err
+			}
+			return uow.Do(ctx, func(ctx context.Context, repos core.Repositories) error {
+				return errors.New("nested error")
+			})
+		})
+		assert.Error(t, err)
+		assert.Equal(t, int64(2), count(&core.Customer{}))
+	})
+
+	t.Run("(fail) batch delete of a missing customer writes no event", func(t *testing.T) {
+		service := core.NewCustomerService(NewGormCustomerRepository(db), uow)
+		audits, events := count(&core.AuditEntry{}), count(&core.OutboxMessage{})
+
+		// ExecuteBatch() for delete a missing customer and check the result of the operation
+		results, err := service.ExecuteBatch(context.Background(), []core.BatchOperation{
+			{Operation: core.BatchOperationDelete, ID: uint(99)},
+		}, false)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, gorm.ErrRecordNotFound)
+		assert.Equal(t, audits, count(&core.AuditEntry{}))
+		assert.Equal(t, events, count(&core.OutboxMessage{}))
+	})
+
+	t.Run("(fail) delete of a missing customer writes no event", func(t *testing.T) {
+		service := core.NewCustomerService(NewGormCustomerRepository(db), uow)
+		audits, events, changes := count(&core.AuditEntry{}), count(&core.OutboxMessage{}), count(&core.ChangeRecord{})
+
+		// DeleteCustomer() for delete a missing customer and check Error
+		err := service.DeleteCustomer(context.Background(), uint(42))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.Equal(t, audits, count(&core.AuditEntry{}))
+		assert.Equal(t, events, count(&core.OutboxMessage{}))
+		assert.Equal(t, changes, count(&core.ChangeRecord{}))
+	})
+}
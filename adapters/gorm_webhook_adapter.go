@@ -0,0 +1,153 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// * Secondary adapter (gorm_webhook_adapter.go)
+
+type GormWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewGormWebhookRepository(db *gorm.DB) core.WebhookRepository {
+	return &GormWebhookRepository{db: db}
+}
+
+// scoped starts the queries of one tenant
+func (r *GormWebhookRepository) scoped(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Where("tenant_id = ?", core.TenantFromContext(ctx))
+}
+
+func (r *GormWebhookRepository) SaveSubscription(ctx context.Context, subscription *core.WebhookSubscription) error {
+	// Insert the subscription into the tenant of ctx in database and check Error
+	subscription.TenantID = core.TenantFromContext(ctx)
+	if err := r.db.WithContext(ctx).Create(subscription).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormWebhookRepository) GetAllSubscriptions(ctx context.Context) ([]core.WebhookSubscription, error) {
+	var subscriptions []core.WebhookSubscription
+
+	// Get all subscriptions of the tenant from database and check Error
+	if err := r.scoped(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		return []core.WebhookSubscription{}, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *GormWebhookRepository) GetSubscription(ctx context.Context, subscriptionId uint) (*core.WebhookSubscription, error) {
+	var subscription core.WebhookSubscription
+
+	// Get a subscription of the tenant from database and check Error
+	if err := r.scoped(ctx).First(&subscription, subscriptionId).Error; err != nil {
+		return &core.WebhookSubscription{}, err
+	}
+
+	return &subscription, nil
+}
+
+func (r *GormWebhookRepository) DeleteSubscription(ctx context.Context, subscriptionId uint) error {
+	// Delete the subscription with the deliveries it still has to send in one transaction and check Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tenantId := core.TenantFromContext(ctx)
+		result := tx.Where("tenant_id = ? AND id = ?", tenantId, subscriptionId).Delete(&core.WebhookSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Where("tenant_id = ? AND subscription_id = ? AND delivered_at IS NULL", tenantId, subscriptionId).
+			Delete(&core.WebhookDelivery{}).Error
+	})
+}
+
+func (r *GormWebhookRepository) SaveDeliveries(ctx context.Context, deliveries []core.WebhookDelivery) error {
+	tenantId := core.TenantFromContext(ctx)
+	for index := range deliveries {
+		deliveries[index].TenantID = tenantId
+	}
+
+	// Insert the deliveries, skip the ones of a subscription and message that exist, and check Error
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GormWebhookRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]core.WebhookDelivery, error) {
+	var deliveries []core.WebhookDelivery
+
+	// Select the due deliveries and push their next attempt behind the lease in one transaction and check Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []core.WebhookDelivery
+		if err := tx.Where("delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= ?", now).Order("id").Limit(limit).Find(&due).Error; err != nil {
+			return err
+		}
+
+		for _, delivery := range due {
+			result := tx.Model(&core.WebhookDelivery{}).
+				Where("id = ? AND next_attempt_at = ?", delivery.ID, delivery.NextAttemptAt).
+				Update("next_attempt_at", now.Add(lease))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []core.WebhookDelivery{}, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *GormWebhookRepository) GetDelivery(ctx context.Context, deliveryId uint) (*core.WebhookDelivery, error) {
+	var delivery core.WebhookDelivery
+
+	// Get a delivery of the tenant from database and check Error
+	if err := r.scoped(ctx).First(&delivery, deliveryId).Error; err != nil {
+		return &core.WebhookDelivery{}, err
+	}
+
+	return &delivery, nil
+}
+
+func (r *GormWebhookRepository) UpdateDelivery(ctx context.Context, delivery *core.WebhookDelivery) error {
+	// Update every column of the delivery in the tenant, nil times are written too, and check Error
+	result := r.scoped(ctx).Model(&core.WebhookDelivery{}).Where("id = ?", delivery.ID).Select("*").Omit("id", "tenant_id", "created_at").Updates(delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GormWebhookRepository) GetDeadDeliveries(ctx context.Context) ([]core.WebhookDelivery, error) {
+	var deliveries []core.WebhookDelivery
+
+	// Get the dead deliveries of the tenant from database and check Error
+	if err := r.scoped(ctx).Where("dead_at IS NOT NULL").Order("id").Find(&deliveries).Error; err != nil {
+		return []core.WebhookDelivery{}, err
+	}
+
+	return deliveries, nil
+}
@@ -1,20 +1,33 @@
 package adapters
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/fiatfour/itmx-crud-hex/core"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // ! Primary adapter (http_adapter.go)
 
 type HttpCustomerHandler struct {
-	service core.CustomerService
+	service     core.CustomerService
+	streamsDone context.Context
 }
 
 func NewHttpCustomerHandler(service core.CustomerService) *HttpCustomerHandler {
-	return &HttpCustomerHandler{service: service}
+	return &HttpCustomerHandler{service: service, streamsDone: context.Background()}
+}
+
+// EndStreamsOn ends the open event streams once ctx is canceled, the server waits for every response while it
+// shuts down and a stream would only end when its client goes away
+func (h *HttpCustomerHandler) EndStreamsOn(ctx context.Context) {
+	h.streamsDone = ctx
 }
 
 func (h *HttpCustomerHandler) CreateCustomerHandler(c *fiber.Ctx) error {
@@ -31,7 +44,10 @@ func (h *HttpCustomerHandler) CreateCustomerHandler(c *fiber.Ctx) error {
 	}
 
 	// call CreateCustomer() to pass agreement of Customer for create in service and check Error
-	if err := h.service.CreateCustomer(customer); err != nil {
+	if err := h.service.CreateCustomer(c.UserContext(), customer); err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -46,8 +62,11 @@ func (h *HttpCustomerHandler) GetCustomerHandler(c *fiber.Ctx) error {
 	}
 
 	// call GetCustomerById() to pass agreement of customerId for get a Customer in service and check Error
-	customer, err := h.service.GetCustomerById(uint(customerId))
+	customer, err := h.service.GetCustomerById(c.UserContext(), uint(customerId))
 	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -58,8 +77,11 @@ func (h *HttpCustomerHandler) GetAllCustomerHandler(c *fiber.Ctx) error {
 	var customers []core.Customer
 
 	// call GetCustomerById() to pass agreement of customerId for get Customers in service and check Error
-	customers, err := h.service.GetAllCustomer()
+	customers, err := h.service.GetAllCustomer(c.UserContext())
 	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -87,13 +109,19 @@ func (h *HttpCustomerHandler) UpdateCustomerHandler(c *fiber.Ctx) error {
 	}
 
 	// call SearchCustomerById() to pass agreement of customerId for search a customer in service and check error
-	if err = h.service.SearchCustomerById(uint(customerId)); err != nil {
+	if err = h.service.SearchCustomerById(c.UserContext(), uint(customerId)); err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusNotFound).SendString(err.Error())
 	}
 
 	// call UpdateCustomer() to pass agreement of customerId with Customer for update a customer in service and get updatedCustomer with check error
-	updatedCustomer, err := h.service.UpdateCustomer(uint(customerId), &customer)
+	updatedCustomer, err := h.service.UpdateCustomer(c.UserContext(), uint(customerId), &customer)
 	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -113,14 +141,179 @@ func (h *HttpCustomerHandler) DeleteCustomerHandler(c *fiber.Ctx) error {
 	}
 
 	// call SearchCustomerById() to pass agreement of customerId for search a customer in service and check error
-	if err = h.service.SearchCustomerById(uint(customerId)); err != nil {
+	if err = h.service.SearchCustomerById(c.UserContext(), uint(customerId)); err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
 		return c.Status(fiber.StatusNotFound).SendString(err.Error())
 	}
 
 	// call DeleteCustomer() to pass agreement of customerId for delete a customer in service and check error
-	if err = h.service.DeleteCustomer(uint(customerId)); err != nil {
+	if err = h.service.DeleteCustomer(c.UserContext(), uint(customerId)); err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		// another request deleted the customer after the search
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.Status(fiber.StatusOK).SendString("Deleted successfully!")
 }
+
+// Modes of POST /customers:batch
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+)
+
+type batchOperationRequest struct {
+	Op       string        `json:"op"`
+	ID       uint          `json:"id"`
+	Customer core.Customer `json:"customer"`
+}
+
+type batchRequest struct {
+	Mode       string                  `json:"mode"`
+	Operations []batchOperationRequest `json:"operations"`
+}
+
+type batchResultResponse struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     uint   `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (h *HttpCustomerHandler) BatchCustomerHandler(c *fiber.Ctx) error {
+	var request batchRequest
+
+	// get a batch from body(json) and check Error
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if request.Mode == "" {
+		request.Mode = BatchModeBestEffort
+	}
+	if request.Mode != BatchModeAllOrNothing && request.Mode != BatchModeBestEffort {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode must be all_or_nothing or best_effort"})
+	}
+
+	operations := make([]core.BatchOperation, len(request.Operations))
+	for index, operation := range request.Operations {
+		operations[index] = core.BatchOperation{Operation: operation.Op, ID: operation.ID, Customer: operation.Customer}
+	}
+
+	// call ExecuteBatch() to pass agreement of operations for apply them in service and check Error
+	results, err := h.service.ExecuteBatch(c.UserContext(), operations, request.Mode == BatchModeAllOrNothing)
+	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		if errors.Is(err, core.ErrBatchEmpty) || errors.Is(err, core.ErrBatchTooLarge) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// report every operation, the status is 200 when all succeeded
+	succeeded := 0
+	response := make([]batchResultResponse, len(results))
+	for index, result := range results {
+		response[index] = batchResultResponse{Index: result.Index, Op: result.Operation, ID: result.ID, Status: "ok"}
+		switch {
+		case errors.Is(result.Err, core.ErrBatchAborted):
+			response[index].Status = "aborted"
+			response[index].Error = result.Err.Error()
+		case errors.Is(result.Err, gorm.ErrRecordNotFound):
+			response[index].Status = "not_found"
+			response[index].Error = result.Err.Error()
+		case result.Err != nil:
+			response[index].Status = "failed"
+			response[index].Error = result.Err.Error()
+		default:
+			succeeded++
+		}
+	}
+
+	status := fiber.StatusOK
+	if succeeded < len(results) {
+		status = fiber.StatusMultiStatus
+		if request.Mode == BatchModeAllOrNothing {
+			status = fiber.StatusUnprocessableEntity
+		}
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"mode":      request.Mode,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   response,
+	})
+}
+
+type importRowErrorResponse struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportCustomersHandler imports the customers of a CSV file sent as the request body (text/csv) or as the
+// "file" field of a multipart form. With ?dry_run=true the rows are only validated
+func (h *HttpCustomerHandler) ImportCustomersHandler(c *fiber.Ctx) error {
+	var body io.Reader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		// get the file from the multipart form and check Error
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+		defer file.Close()
+		body = file
+	} else if stream := c.Context().RequestBodyStream(); stream != nil {
+		body = stream
+	} else {
+		body = bytes.NewReader(c.Body())
+	}
+
+	// read the header of the csv and check Error
+	source, err := NewCSVCustomerSource(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// call ImportCustomers() to pass agreement of rows for validate and insert them in service and check Error
+	report, err := h.service.ImportCustomers(c.UserContext(), source, c.QueryBool("dry_run"))
+	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		// rows of the batches committed before the error stay imported
+		imported := 0
+		if report != nil {
+			imported = report.Imported
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "imported": imported})
+	}
+
+	rowErrors := make([]importRowErrorResponse, len(report.Errors))
+	for index, rowErr := range report.Errors {
+		rowErrors[index] = importRowErrorResponse{Line: rowErr.Line, Error: rowErr.Err.Error()}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"dry_run":          report.DryRun,
+		"rows":             report.Rows,
+		"valid":            report.Valid,
+		"imported":         report.Imported,
+		"failed":           report.Failed,
+		"errors":           rowErrors,
+		"errors_truncated": report.ErrorsTruncated,
+	})
+}
@@ -2,18 +2,23 @@ package adapters
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/fiatfour/itmx-crud-hex/core"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockCustomerService is a mock implementation of core.CustomerService
@@ -21,32 +26,32 @@ type MockCustomerService struct {
 	mock.Mock
 }
 
-func (m *MockCustomerService) CreateCustomer(customer core.Customer) error {
+func (m *MockCustomerService) CreateCustomer(ctx context.Context, customer core.Customer) error {
 	args := m.Called(customer)
 	return args.Error(0)
 }
 
-func (m *MockCustomerService) GetCustomerById(customerId uint) (*core.Customer, error) {
+func (m *MockCustomerService) GetCustomerById(ctx context.Context, customerId uint) (*core.Customer, error) {
 	args := m.Called(customerId)
 	return args.Get(0).(*core.Customer), args.Error(1)
 }
 
-func (m *MockCustomerService) GetAllCustomer() ([]core.Customer, error) {
+func (m *MockCustomerService) GetAllCustomer(ctx context.Context) ([]core.Customer, error) {
 	args := m.Called()
 	return args.Get(0).([]core.Customer), args.Error(1)
 }
 
-func (m *MockCustomerService) UpdateCustomer(customerId uint, customer *core.Customer) (*core.Customer, error) {
+func (m *MockCustomerService) UpdateCustomer(ctx context.Context, customerId uint, customer *core.Customer) (*core.Customer, error) {
 	args := m.Called(customerId, customer)
 	return args.Get(0).(*core.Customer), args.Error(1)
 }
 
-func (m *MockCustomerService) DeleteCustomer(customerId uint) error {
+func (m *MockCustomerService) DeleteCustomer(ctx context.Context, customerId uint) error {
 	args := m.Called(customerId)
 	return args.Error(0)
 }
 
-func (m *MockCustomerService) SearchCustomerById(customerId uint) error {
+func (m *MockCustomerService) SearchCustomerById(ctx context.Context, customerId uint) error {
 	args := m.Called(customerId)
 	return args.Error(0)
 }
@@ -56,6 +61,33 @@ func (m *MockCustomerService) ValidateName(customerName string) error {
 	return args.Error(0)
 }
 
+func (m *MockCustomerService) ExecuteBatch(ctx context.Context, operations []core.BatchOperation, atomic bool) ([]core.BatchResult, error) {
+	args := m.Called(operations, atomic)
+	return args.Get(0).([]core.BatchResult), args.Error(1)
+}
+
+func (m *MockCustomerService) ImportCustomers(ctx context.Context, source core.CustomerSource, dryRun bool) (*core.ImportReport, error) {
+	args := m.Called(source, dryRun)
+	return args.Get(0).(*core.ImportReport), args.Error(1)
+}
+
+func (m *MockCustomerService) ExportCustomers(ctx context.Context) (core.CustomerStream, error) {
+	args := m.Called()
+	stream, _ := args.Get(0).(core.CustomerStream)
+	return stream, args.Error(1)
+}
+
+func (m *MockCustomerService) GetChanges(ctx context.Context, since uint, limit int) ([]core.ChangeRecord, error) {
+	args := m.Called(since, limit)
+	return args.Get(0).([]core.ChangeRecord), args.Error(1)
+}
+
+func (m *MockCustomerService) StreamCustomerEvents(ctx context.Context, lastEventId *uint) (core.CustomerEventStream, error) {
+	args := m.Called(lastEventId)
+	stream, _ := args.Get(0).(core.CustomerEventStream)
+	return stream, args.Error(1)
+}
+
 // SetupTestApp initializes the Fiber app with the necessary routes and handlers for testing
 func SetupTestApp(service core.CustomerService) *fiber.App {
 	// initialize a new Fiber app
@@ -66,6 +98,11 @@ func SetupTestApp(service core.CustomerService) *fiber.App {
 
 	// set up routes
 	app.Post("/customers", customerHandler.CreateCustomerHandler)
+	app.Post("/customers\\:batch", customerHandler.BatchCustomerHandler)
+	app.Post("/customers\\:import", customerHandler.ImportCustomersHandler)
+	app.Get("/customers/export", customerHandler.ExportCustomersHandler)
+	app.Get("/customers/stream", customerHandler.StreamCustomerEventsHandler)
+	app.Get("/changes", customerHandler.GetChangesHandler)
 	app.Get("/customers/:id", customerHandler.GetCustomerHandler)
 	app.Get("/customers", customerHandler.GetAllCustomerHandler)
 	app.Put("/customers/:id", customerHandler.UpdateCustomerHandler)
@@ -510,4 +547,427 @@ func TestDeleteCustomerHandler(t *testing.T) {
 		// check all mocked it's work on expected
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("(fail) forbidden", func(t *testing.T) {
+		// mock clear
+		mockService.ExpectedCalls = nil
+		// setup customerId
+		customerId := uint(1)
+
+		// mock service
+		mockService.On("SearchCustomerById", customerId).Return(nil)
+		mockService.On("DeleteCustomer", customerId).Return(fmt.Errorf("%w: missing permission customers:delete", core.ErrForbidden))
+
+		// create a new HTTP Delete request and send that will return value of Response(Status) with Error to check
+		req := httptest.NewRequest("DELETE", "/customers/1", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, MIMEApplicationProblemJSON, resp.Header.Get("Content-Type"))
+
+		// decode Problem response from body and then check Value/Error
+		var problem Problem
+		err = json.NewDecoder(resp.Body).Decode(&problem)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, problem.Status)
+		assert.Equal(t, "Forbidden", problem.Title)
+		assert.Equal(t, "forbidden: missing permission customers:delete", problem.Detail)
+		// check all mocked it's work on expected
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestBatchCustomerHandler(t *testing.T) {
+	// mock
+	mockService := new(MockCustomerService)
+	app := SetupTestApp(mockService)
+	body := `{"mode": "%s", "operations": [{"op": "create", "customer": {"name": "Fiat", "age": 24}}, {"op": "delete", "id": 2}]}`
+	operations := []core.BatchOperation{
+		{Operation: core.BatchOperationCreate, Customer: core.Customer{Name: "Fiat", Age: 24}},
+		{Operation: core.BatchOperationDelete, ID: 2},
+	}
+	send := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/customers:batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response
+	}
+
+	// Success case
+	t.Run("successful batch", func(t *testing.T) {
+		// Mock service
+		mockService.On("ExecuteBatch", operations, true).Return([]core.BatchResult{
+			{Index: 0, Operation: core.BatchOperationCreate, ID: 1},
+			{Index: 1, Operation: core.BatchOperationDelete, ID: 2},
+		}, nil)
+
+		status, response := send(fmt.Sprintf(body, BatchModeAllOrNothing))
+
+		// check Status and Response
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, float64(2), response["succeeded"])
+		assert.Equal(t, float64(1), response["results"].([]interface{})[0].(map[string]interface{})["id"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("successful best effort with a failed operation", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ExecuteBatch", operations, false).Return([]core.BatchResult{
+			{Index: 0, Operation: core.BatchOperationCreate, Err: core.ErrNameAlreadyExists},
+			{Index: 1, Operation: core.BatchOperationDelete, ID: 2},
+		}, nil)
+
+		status, response := send(fmt.Sprintf(body, BatchModeBestEffort))
+
+		// check Status and Response
+		assert.Equal(t, fiber.StatusMultiStatus, status)
+		assert.Equal(t, float64(1), response["failed"])
+		result := response["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "failed", result["status"])
+		assert.Equal(t, "name already exists", result["error"])
+		mockService.AssertExpectations(t)
+	})
+
+	// Failure case
+	t.Run("(fail) all or nothing with a failed operation", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ExecuteBatch", operations, true).Return([]core.BatchResult{
+			{Index: 0, Operation: core.BatchOperationCreate, Err: core.ErrBatchAborted},
+			{Index: 1, Operation: core.BatchOperationDelete, ID: 2, Err: errors.New("database error")},
+		}, nil)
+
+		status, response := send(fmt.Sprintf(body, BatchModeAllOrNothing))
+
+		// check Status and Response
+		assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+		result := response["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "aborted", result["status"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("(fail) delete of a missing customer", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ExecuteBatch", operations, false).Return([]core.BatchResult{
+			{Index: 0, Operation: core.BatchOperationCreate, ID: 1},
+			{Index: 1, Operation: core.BatchOperationDelete, ID: 2, Err: gorm.ErrRecordNotFound},
+		}, nil)
+
+		status, response := send(fmt.Sprintf(body, BatchModeBestEffort))
+
+		// check Status and Response
+		assert.Equal(t, fiber.StatusMultiStatus, status)
+		result := response["results"].([]interface{})[1].(map[string]interface{})
+		assert.Equal(t, "not_found", result["status"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("(fail) invalid mode", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+
+		status, _ := send(fmt.Sprintf(body, "sometimes"))
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("(fail) empty batch", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ExecuteBatch", []core.BatchOperation{}, false).Return([]core.BatchResult{}, core.ErrBatchEmpty)
+
+		status, _ := send(`{"operations": []}`)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestImportCustomersHandler(t *testing.T) {
+	// mock
+	mockService := new(MockCustomerService)
+	app := SetupTestApp(mockService)
+	send := func(url string, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		var response map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response
+	}
+
+	// Success case
+	t.Run("successful dry run", func(t *testing.T) {
+		// Mock service
+		mockService.On("ImportCustomers", mock.AnythingOfType("*adapters.CSVCustomerSource"), true).Return(&core.ImportReport{
+			DryRun: true, Rows: 2, Valid: 1, Failed: 1,
+			Errors: []core.ImportRowError{{Line: 3, Err: core.ErrInvalidAge}},
+		}, nil)
+
+		status, response := send("/customers:import?dry_run=true", "name,age\nFiat,24\nAnfat,0\n")
+
+		// check Status and Response
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, true, response["dry_run"])
+		assert.Equal(t, float64(1), response["valid"])
+		rowErr := response["errors"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(3), rowErr["line"])
+		assert.Equal(t, "age must more than 0", rowErr["error"])
+		mockService.AssertExpectations(t)
+	})
+
+	// Failure case
+	t.Run("(fail) missing column", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+
+		status, response := send("/customers:import", "name,birthday\nFiat,1999-01-01\n")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Equal(t, "csv header is missing a required column: age", response["error"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("(fail) customer service error", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ImportCustomers", mock.Anything, false).Return(&core.ImportReport{Imported: 500}, errors.New("database error"))
+
+		status, response := send("/customers:import", "name,age\nFiat,24\n")
+		assert.Equal(t, fiber.StatusInternalServerError, status)
+		assert.Equal(t, float64(500), response["imported"])
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestExportCustomersHandler(t *testing.T) {
+	// mock
+	mockService := new(MockCustomerService)
+	app := SetupTestApp(mockService)
+	stream := core.CustomerStream(func(fn func([]core.Customer) error) error {
+		if err := fn([]core.Customer{{ID: 1, Name: "Fiat", Age: 24}}); err != nil {
+			return err
+		}
+		return fn([]core.Customer{{ID: 2, Name: "Anfat Nilaingan", Age: 40}})
+	})
+	send := func(accept string) (*http.Response, string) {
+		req := httptest.NewRequest("GET", "/customers/export", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// Success case
+	t.Run("successful as json by default", func(t *testing.T) {
+		// Mock service
+		mockService.On("ExportCustomers").Return(stream, nil)
+
+		resp, body := send("")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get("Content-Type"))
+
+		var customers []core.Customer
+		assert.NoError(t, json.Unmarshal([]byte(body), &customers))
+		assert.Equal(t, []core.Customer{{ID: 1, Name: "Fiat", Age: 24}, {ID: 2, Name: "Anfat Nilaingan", Age: 40}}, customers)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("successful as ndjson", func(t *testing.T) {
+		resp, body := send(MIMEApplicationNDJSON)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, MIMEApplicationNDJSON, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "{\"ID\":1,\"Name\":\"Fiat\",\"Age\":24}\n{\"ID\":2,\"Name\":\"Anfat Nilaingan\",\"Age\":40}\n", body)
+	})
+
+	t.Run("successful as csv", func(t *testing.T) {
+		resp, body := send("text/csv, application/json;q=0.5")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, MIMETextCSV, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "id,name,age\n1,Fiat,24\n2,Anfat Nilaingan,40\n", body)
+	})
+
+	// Failure case
+	t.Run("(fail) not acceptable", func(t *testing.T) {
+		resp, _ := send("application/xml")
+		assert.Equal(t, fiber.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	t.Run("(fail) forbidden", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("ExportCustomers").Return(nil, fmt.Errorf("%w: missing permission customers:read", core.ErrForbidden))
+
+		resp, _ := send("")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestStreamCustomerEventsHandler(t *testing.T) {
+	// mock
+	mockService := new(MockCustomerService)
+	app := SetupTestApp(mockService)
+	eventId := func(id uint) *uint {
+		return &id
+	}
+	// the stream sends one batch and an empty poll, then ends like a stream whose client is gone
+	stream := core.CustomerEventStream(func(fn func([]core.StreamedEvent) error) error {
+		occurredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		err := fn([]core.StreamedEvent{
+			{ID: 6, Event: core.CustomerEvent{Type: core.EventCustomerCreated, CustomerID: 3, Customer: &core.Customer{ID: 3, Name: "Fiat", Age: 24}, OccurredAt: occurredAt}},
+			{ID: 7, Event: core.CustomerEvent{Type: core.EventCustomerDeleted, CustomerID: 3, OccurredAt: occurredAt}},
+		})
+		if err != nil {
+			return err
+		}
+		return fn([]core.StreamedEvent{})
+	})
+	send := func(header string, query string) (*http.Response, string) {
+		req := httptest.NewRequest("GET", "/customers/stream"+query, nil)
+		if header != "" {
+			req.Header.Set(HeaderLastEventID, header)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// Success case
+	t.Run("successful resumed after Last-Event-ID", func(t *testing.T) {
+		// Mock service
+		mockService.On("StreamCustomerEvents", eventId(5)).Return(stream, nil)
+
+		resp, body := send("5", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, MIMETextEventStream, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "retry: 3000\n\n"+
+			"id: 6\nevent: CustomerCreated\ndata: {\"type\":\"CustomerCreated\",\"tenant_id\":\"\",\"customer_id\":3,\"customer\":{\"ID\":3,\"Name\":\"Fiat\",\"Age\":24},\"occurred_at\":\"2024-01-01T00:00:00Z\"}\n\n"+
+			"id: 7\nevent: CustomerDeleted\ndata: {\"type\":\"CustomerDeleted\",\"tenant_id\":\"\",\"customer_id\":3,\"occurred_at\":\"2024-01-01T00:00:00Z\"}\n\n", body)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("successful from the head or the last_event_id query", func(t *testing.T) {
+		// Mock service, without last event id the stream starts at the head of the change log
+		mockService.On("StreamCustomerEvents", (*uint)(nil)).Return(stream, nil)
+		mockService.On("StreamCustomerEvents", eventId(0)).Return(stream, nil)
+		mockService.On("StreamCustomerEvents", eventId(2)).Return(stream, nil)
+
+		resp, _ := send("", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		resp, _ = send("", "?last_event_id=2")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		resp, _ = send("0", "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid last event id", func(t *testing.T) {
+		resp, body := send("abc", "")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.JSONEq(t, `{"error": "last event id must be a positive integer"}`, body)
+	})
+
+	t.Run("(fail) forbidden", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("StreamCustomerEvents", (*uint)(nil)).Return(nil, fmt.Errorf("%w: missing permission customers:read", core.ErrForbidden))
+
+		resp, _ := send("", "")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestGetChangesHandler(t *testing.T) {
+	// mock
+	mockService := new(MockCustomerService)
+	app := SetupTestApp(mockService)
+	changedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	send := func(query string) (*http.Response, string) {
+		req := httptest.NewRequest("GET", "/changes"+query, nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// Success case
+	t.Run("successful with tombstone", func(t *testing.T) {
+		// Mock service
+		mockService.On("GetChanges", uint(3), 2).Return([]core.ChangeRecord{
+			{Sequence: 4, Operation: core.ChangeUpdate, CustomerID: 1, Customer: &core.Customer{ID: 1, Name: "Fiat", Age: 25}, ChangedAt: changedAt},
+			{Sequence: 5, Operation: core.ChangeDelete, CustomerID: 1, Deleted: true, ChangedAt: changedAt},
+		}, nil)
+
+		resp, body := send("?since=3&limit=2")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"changes": [
+			{"seq": 4, "op": "update", "customer_id": 1, "customer": {"ID": 1, "Name": "Fiat", "Age": 25}, "deleted": false, "changed_at": "2024-01-01T00:00:00Z"},
+			{"seq": 5, "op": "delete", "customer_id": 1, "deleted": true, "changed_at": "2024-01-01T00:00:00Z"}
+		], "next_since": 5}`, body)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("successful caught up keeps since", func(t *testing.T) {
+		// Mock service
+		mockService.On("GetChanges", uint(5), 0).Return([]core.ChangeRecord{}, nil)
+
+		resp, body := send("?since=5")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"changes": [], "next_since": 5}`, body)
+		mockService.AssertExpectations(t)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid since", func(t *testing.T) {
+		resp, body := send("?since=-1")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.JSONEq(t, `{"error": "since must be a positive integer"}`, body)
+	})
+
+	t.Run("(fail) invalid limit", func(t *testing.T) {
+		// Mock service
+		mockService.On("GetChanges", uint(0), 5000).Return([]core.ChangeRecord{}, core.ErrInvalidChangeLimit)
+
+		resp, body := send("?limit=5000")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.JSONEq(t, `{"error": "limit must be between 1 and 1000"}`, body)
+
+		resp, _ = send("?limit=ten")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("(fail) forbidden", func(t *testing.T) {
+		// clear mock
+		mockService.ExpectedCalls = nil
+		// Mock service
+		mockService.On("GetChanges", uint(0), 0).Return([]core.ChangeRecord{}, fmt.Errorf("%w: missing permission customers:read", core.ErrForbidden))
+
+		resp, _ := send("")
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
 }
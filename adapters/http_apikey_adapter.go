@@ -0,0 +1,139 @@
+package adapters
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ! Primary adapter (http_apikey_adapter.go)
+
+type HttpApiKeyHandler struct {
+	service core.ApiKeyService
+}
+
+func NewHttpApiKeyHandler(service core.ApiKeyService) *HttpApiKeyHandler {
+	return &HttpApiKeyHandler{service: service}
+}
+
+// apiKeyRequest is the body of issue and rotate, TTL is a Go duration (e.g. "720h") and empty means no expiry.
+// The key always belongs to the tenant of the admin
+type apiKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	TTL    string   `json:"ttl"`
+}
+
+func (r apiKeyRequest) ttl() (time.Duration, error) {
+	if r.TTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(r.TTL)
+	if err != nil || ttl < 0 {
+		return 0, errors.New("invalid ttl")
+	}
+	return ttl, nil
+}
+
+// apiKeyResponse shows the plain key, it is only returned once on issue and rotate
+func apiKeyResponse(apiKey *core.ApiKey, rawKey string) fiber.Map {
+	return fiber.Map{
+		"id":         apiKey.ID,
+		"tenant_id":  apiKey.TenantID,
+		"name":       apiKey.Name,
+		"key":        rawKey,
+		"scopes":     apiKey.Scopes,
+		"expires_at": apiKey.ExpiresAt,
+	}
+}
+
+func (h *HttpApiKeyHandler) IssueApiKeyHandler(c *fiber.Ctx) error {
+	var request apiKeyRequest
+
+	// get a request from body(json) and check Error
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	ttl, err := request.ttl()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// call IssueApiKey() to create a key of the tenant of the admin in service and check Error
+	apiKey, rawKey, err := h.service.IssueApiKey(c.UserContext(), request.Name, request.Scopes, ttl)
+	if err != nil {
+		if errors.Is(err, core.ErrApiKeyNameEmpty) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(apiKeyResponse(apiKey, rawKey))
+}
+
+func (h *HttpApiKeyHandler) GetAllApiKeyHandler(c *fiber.Ctx) error {
+	// call GetAllApiKey() for get ApiKeys in service and check Error
+	apiKeys, err := h.service.GetAllApiKey(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(apiKeys)
+}
+
+func (h *HttpApiKeyHandler) RevokeApiKeyHandler(c *fiber.Ctx) error {
+	// get Id and check Error
+	apiKeyId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	// call RevokeApiKey() to revoke a key in service and check Error
+	if err := h.service.RevokeApiKey(c.UserContext(), uint(apiKeyId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).SendString("Revoked successfully!")
+}
+
+func (h *HttpApiKeyHandler) RotateApiKeyHandler(c *fiber.Ctx) error {
+	var request apiKeyRequest
+
+	// get Id and check Error
+	apiKeyId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	// the body is optional for rotate, it only carries the new ttl
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+		}
+	}
+	ttl, err := request.ttl()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// call RotateApiKey() to replace the secret of a key in service and check Error
+	apiKey, rawKey, err := h.service.RotateApiKey(c.UserContext(), uint(apiKeyId), ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, core.ErrApiKeyRevoked):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(apiKeyResponse(apiKey, rawKey))
+}
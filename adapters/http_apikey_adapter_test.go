@@ -0,0 +1,193 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupApiKeyTestApp wires the admin routes and a protected route the same way main.go does
+func setupApiKeyTestApp(t *testing.T, keys testKeys) *fiber.App {
+	keySet, err := LoadJWKS(keys.jwksPath)
+	require.NoError(t, err)
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// close the shared in-memory database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	service := core.NewApiKeyService(NewGormApiKeyRepository(db))
+	handler := NewHttpApiKeyHandler(service)
+	jwtAuth := NewJWTMiddleware(JWTConfig{KeySet: keySet})
+
+	app := fiber.New()
+	apiKeys := app.Group("/admin/api-keys", jwtAuth, NewRequireRoleMiddleware("admin"))
+	apiKeys.Post("/", handler.IssueApiKeyHandler)
+	apiKeys.Get("/", handler.GetAllApiKeyHandler)
+	apiKeys.Delete("/:id", handler.RevokeApiKeyHandler)
+	apiKeys.Post("/:id/rotate", handler.RotateApiKeyHandler)
+
+	app.Get("/protected", NewApiKeyMiddleware(service), jwtAuth, func(c *fiber.Ctx) error {
+		principal, _ := core.PrincipalFromContext(c.UserContext())
+		return c.SendString(principal.Subject)
+	})
+	return app
+}
+
+func adminToken(t *testing.T, keys testKeys, roles ...string) string {
+	claims := validClaims()
+	claims["roles"] = roles
+	return signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, claims)
+}
+
+func TestHttpApiKeyHandler(t *testing.T) {
+	keys := setupTestKeys(t)
+	app := setupApiKeyTestApp(t, keys)
+	token := adminToken(t, keys, "admin")
+
+	otherClaims := validClaims()
+	otherClaims["tenant_id"] = "bank-b"
+	otherClaims["roles"] = []string{"admin"}
+	otherTenantToken := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, otherClaims)
+
+	adminOf := func(token string, method, path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		var response map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response
+	}
+	admin := func(method, path, body string) (int, map[string]interface{}) {
+		return adminOf(token, method, path, body)
+	}
+	protected := func(rawKey string) int {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set(ApiKeyHeader, rawKey)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	var firstKey string
+
+	// Success case
+	t.Run("successful issue and authenticate", func(t *testing.T) {
+		status, response := admin("POST", "/admin/api-keys", `{"name": "batch partner", "scopes": ["customers:read"], "ttl": "24h"}`)
+		assert.Equal(t, fiber.StatusCreated, status)
+		assert.Equal(t, "batch partner", response["name"])
+		assert.Equal(t, "bank-a", response["tenant_id"])
+		assert.NotNil(t, response["expires_at"])
+		firstKey = response["key"].(string)
+
+		assert.Equal(t, fiber.StatusOK, protected(firstKey))
+	})
+
+	t.Run("successful list without secrets", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/api-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		var apiKeys []map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiKeys))
+		assert.Len(t, apiKeys, 1)
+		assert.NotContains(t, apiKeys[0], "SecretHash")
+		assert.NotNil(t, apiKeys[0]["LastUsedAt"])
+	})
+
+	t.Run("successful rotate invalidates the old key", func(t *testing.T) {
+		status, response := admin("POST", "/admin/api-keys/1/rotate", "")
+		assert.Equal(t, fiber.StatusOK, status)
+		rotatedKey := response["key"].(string)
+
+		assert.Equal(t, fiber.StatusUnauthorized, protected(firstKey))
+		assert.Equal(t, fiber.StatusOK, protected(rotatedKey))
+		firstKey = rotatedKey
+	})
+
+	t.Run("successful revoke", func(t *testing.T) {
+		status, _ := admin("DELETE", "/admin/api-keys/1", "")
+		assert.Equal(t, fiber.StatusOK, status)
+
+		assert.Equal(t, fiber.StatusUnauthorized, protected(firstKey))
+	})
+
+	// Failure case
+	t.Run("(fail) keys of another tenant", func(t *testing.T) {
+		// an admin of bank-b neither sees, rotates nor revokes the keys of bank-a
+		req := httptest.NewRequest("GET", "/admin/api-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+otherTenantToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var apiKeys []map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiKeys))
+		assert.Empty(t, apiKeys)
+
+		status, _ := adminOf(otherTenantToken, "POST", "/admin/api-keys/1/rotate", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+		status, _ = adminOf(otherTenantToken, "DELETE", "/admin/api-keys/1", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("(fail) issue a key for another tenant", func(t *testing.T) {
+		// tenant_id of the body is ignored, the key belongs to the tenant of the admin
+		status, response := admin("POST", "/admin/api-keys", `{"tenant_id": "bank-b", "name": "foreign partner"}`)
+		assert.Equal(t, fiber.StatusCreated, status)
+		assert.Equal(t, "bank-a", response["tenant_id"])
+	})
+
+	t.Run("(fail) rotate a revoked key", func(t *testing.T) {
+		status, _ := admin("POST", "/admin/api-keys/1/rotate", "")
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+
+	t.Run("(fail) revoke unknown key", func(t *testing.T) {
+		status, _ := admin("DELETE", "/admin/api-keys/999", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("(fail) issue without name", func(t *testing.T) {
+		status, _ := admin("POST", "/admin/api-keys", `{"scopes": ["customers:read"]}`)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("(fail) invalid ttl", func(t *testing.T) {
+		status, _ := admin("POST", "/admin/api-keys", `{"name": "batch partner", "ttl": "forever"}`)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("(fail) admin routes require the admin role", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/api-keys", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken(t, keys, "operator"))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("(fail) unknown api key", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set(ApiKeyHeader, fmt.Sprintf("itmx_%s_%s", "000000000000", "secret"))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, apiKeyChallenge, resp.Header.Get("WWW-Authenticate"))
+	})
+
+	t.Run("(fail) no credentials", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/protected", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,49 @@
+package adapters
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (http_change_adapter.go)
+
+// changesResponse is a page of the change feed, a sync job passes next_since as since of its next request and is
+// caught up once a page has fewer changes than its limit
+type changesResponse struct {
+	Changes   []core.ChangeRecord `json:"changes"`
+	NextSince uint                `json:"next_since"`
+}
+
+// GetChangesHandler returns the changes of customers after the sequence since in order, deletes are tombstones
+func (h *HttpCustomerHandler) GetChangesHandler(c *fiber.Ctx) error {
+	// get since and limit and check Error
+	since, err := strconv.ParseUint(c.Query("since", "0"), 10, 0)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "since must be a positive integer"})
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "0"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": core.ErrInvalidChangeLimit.Error()})
+	}
+
+	// call GetChanges() to get the page of changes from service and check Error
+	changes, err := h.service.GetChanges(c.UserContext(), uint(since), limit)
+	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		if errors.Is(err, core.ErrInvalidChangeLimit) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := changesResponse{Changes: changes, NextSince: uint(since)}
+	if len(changes) > 0 {
+		response.NextSince = changes[len(changes)-1].Sequence
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"bufio"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (http_export_adapter.go)
+
+// Formats of GET /customers/export, chosen by the Accept header, JSON when no header is sent
+const (
+	MIMETextCSV           = "text/csv"
+	MIMEApplicationNDJSON = "application/x-ndjson"
+)
+
+// ExportCustomersHandler streams all customers as JSON array, NDJSON or CSV. The rows are written batch by batch
+// while they are read, so the memory used does not grow with the number of customers. An error after the first
+// batch can not change the status any more, the response is then cut short
+func (h *HttpCustomerHandler) ExportCustomersHandler(c *fiber.Ctx) error {
+	format := c.Accepts(fiber.MIMEApplicationJSON, MIMEApplicationNDJSON, MIMETextCSV)
+	if format == "" {
+		return c.Status(fiber.StatusNotAcceptable).JSON(fiber.Map{"error": "export is available as application/json, application/x-ndjson or text/csv"})
+	}
+
+	// call ExportCustomers() to get the stream of Customers from service and check Error
+	stream, err := h.service.ExportCustomers(c.UserContext())
+	if err != nil {
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, format)
+	if format == MIMETextCSV {
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="customers.csv"`)
+	}
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// an error means the client is gone, the response is cut short
+		WriteCustomers(w, format, stream)
+	})
+
+	return nil
+}
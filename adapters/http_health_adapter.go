@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (http_health_adapter.go)
+
+type HttpHealthHandler struct {
+	service core.HealthService
+}
+
+func NewHttpHealthHandler(service core.HealthService) *HttpHealthHandler {
+	return &HttpHealthHandler{service: service}
+}
+
+// LivenessHandler answers GET /healthz, the orchestrator restarts the process when it does not answer
+func (h *HttpHealthHandler) LivenessHandler(c *fiber.Ctx) error {
+	return healthResponse(c, h.service.Live(c.UserContext()))
+}
+
+// ReadinessHandler answers GET /readyz with the report of every check, 503 takes the instance out of the load
+// balancer until its dependencies are back
+func (h *HttpHealthHandler) ReadinessHandler(c *fiber.Ctx) error {
+	return healthResponse(c, h.service.Ready(c.UserContext()))
+}
+
+func healthResponse(c *fiber.Ctx, report core.HealthReport) error {
+	// probes must never see a cached report
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if !report.Up() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpHealthHandler(t *testing.T) {
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	newApp := func(checks ...core.HealthCheck) *fiber.App {
+		handler := NewHttpHealthHandler(core.NewHealthService(time.Second, checks...))
+		app := fiber.New()
+		app.Get("/healthz", handler.LivenessHandler)
+		app.Get("/readyz", handler.ReadinessHandler)
+		return app
+	}
+	database := core.NewDatabaseCheck(NewGormHealthRepository(db))
+
+	// Success case
+	t.Run("successful ready", func(t *testing.T) {
+		app := newApp(database)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+
+		var report core.HealthReport
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.Equal(t, core.HealthStatusUp, report.Status)
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, core.HealthStatusUp, report.Checks[0].Status)
+	})
+
+	// Failure case
+	t.Run("(fail) ready with a failing check", func(t *testing.T) {
+		stopped := func(ctx context.Context) error { return errors.New("worker outbox relay stopped") }
+		app := newApp(database, core.HealthCheck{Name: "workers", Check: stopped})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+		var report core.HealthReport
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.Equal(t, core.HealthStatusDown, report.Status)
+		assert.Equal(t, core.HealthStatusUp, report.Checks[0].Status)
+		assert.Equal(t, "worker outbox relay stopped", report.Checks[1].Error)
+
+		// liveness does not depend on the checks
+		resp, err = app.Test(httptest.NewRequest("GET", "/healthz", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
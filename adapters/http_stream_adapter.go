@@ -0,0 +1,102 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (http_stream_adapter.go)
+
+// HeaderLastEventID is sent by EventSource clients when they reconnect, with the id of the last event they got
+const HeaderLastEventID = "Last-Event-ID"
+
+// MIMETextEventStream is the type of the responses of GET /customers/stream
+const MIMETextEventStream = "text/event-stream"
+
+// Timings of the event streams, the comment sent while no event happens lets proxies keep the connection open and
+// tells the server that a client is gone
+const (
+	eventStreamRetry     = 3 * time.Second
+	eventStreamHeartbeat = 15 * time.Second
+)
+
+// StreamCustomerEventsHandler sends the creates, updates and deletes of customers as server-sent events, the id of
+// every event is its sequence in the change log (see GET /changes). A client resumes after the id of the
+// Last-Event-ID header, or of the last_event_id query for the first connect, without either it only gets the
+// events that happen after it connected
+func (h *HttpCustomerHandler) StreamCustomerEventsHandler(c *fiber.Ctx) error {
+	var afterId *uint
+	if lastEventId := c.Get(HeaderLastEventID, c.Query("last_event_id")); lastEventId != "" {
+		parsed, err := strconv.ParseUint(lastEventId, 10, 0)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "last event id must be a positive integer"})
+		}
+		id := uint(parsed)
+		afterId = &id
+	}
+
+	// call StreamCustomerEvents() to get the stream of events from service and check Error
+	ctx, cancel := context.WithCancel(c.UserContext())
+	stream, err := h.service.StreamCustomerEvents(ctx, afterId)
+	if err != nil {
+		cancel()
+		if isAuthorizationError(err) {
+			return authorizationProblem(c, err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, MIMETextEventStream)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		// the stream ends when the server shuts down, the client reconnects to another instance with Last-Event-ID
+		stop := context.AfterFunc(h.streamsDone, cancel)
+		defer stop()
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry.Milliseconds())
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		lastWrite := time.Now()
+		stream(func(events []core.StreamedEvent) error {
+			if len(events) == 0 {
+				if time.Since(lastWrite) < eventStreamHeartbeat {
+					return nil
+				}
+				w.WriteString(": keep-alive\n\n")
+			}
+			for _, event := range events {
+				if err := writeServerSentEvent(w, event); err != nil {
+					return err
+				}
+			}
+
+			// send the events to the client, an error means the client is gone and stops the stream
+			lastWrite = time.Now()
+			return w.Flush()
+		})
+	})
+
+	return nil
+}
+
+// writeServerSentEvent writes one event, the data is the customer event as JSON on a single line
+func writeServerSentEvent(w *bufio.Writer, event core.StreamedEvent) error {
+	data, err := json.Marshal(event.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Event.Type, data)
+	return err
+}
@@ -0,0 +1,111 @@
+package adapters
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ! Primary adapter (http_webhook_adapter.go)
+
+type HttpWebhookHandler struct {
+	service core.WebhookService
+}
+
+func NewHttpWebhookHandler(service core.WebhookService) *HttpWebhookHandler {
+	return &HttpWebhookHandler{service: service}
+}
+
+type webhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (h *HttpWebhookHandler) CreateSubscriptionHandler(c *fiber.Ctx) error {
+	var request webhookSubscriptionRequest
+
+	// get a request from body(json) and check Error
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	// call CreateSubscription() to subscribe the url in service and check Error
+	subscription, secret, err := h.service.CreateSubscription(c.UserContext(), request.URL, request.EventTypes)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidWebhookURL) || errors.Is(err, core.ErrWebhookAddressForbidden) || errors.Is(err, core.ErrInvalidWebhookEventType) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// the secret is only returned once
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"id":          subscription.ID,
+		"url":         subscription.URL,
+		"event_types": subscription.EventTypes,
+		"secret":      secret,
+		"created_at":  subscription.CreatedAt,
+	})
+}
+
+func (h *HttpWebhookHandler) GetAllSubscriptionsHandler(c *fiber.Ctx) error {
+	// call GetAllSubscriptions() for get the subscriptions in service and check Error
+	subscriptions, err := h.service.GetAllSubscriptions(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(subscriptions)
+}
+
+func (h *HttpWebhookHandler) DeleteSubscriptionHandler(c *fiber.Ctx) error {
+	// get Id and check Error
+	subscriptionId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	// call DeleteSubscription() to delete a subscription in service and check Error
+	if err := h.service.DeleteSubscription(c.UserContext(), uint(subscriptionId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).SendString("Deleted successfully!")
+}
+
+func (h *HttpWebhookHandler) GetDeadLettersHandler(c *fiber.Ctx) error {
+	// call GetDeadLetters() for get the deliveries that ran out of attempts in service and check Error
+	deliveries, err := h.service.GetDeadLetters(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(deliveries)
+}
+
+func (h *HttpWebhookHandler) RedeliverHandler(c *fiber.Ctx) error {
+	// get Id and check Error
+	deliveryId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	// call Redeliver() to queue a dead delivery again in service and check Error
+	if err := h.service.Redeliver(c.UserContext(), uint(deliveryId)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, core.ErrWebhookDeliveryNotDead):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"id": deliveryId, "status": "queued"})
+}
@@ -0,0 +1,207 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver is a partner endpoint that checks the signature of every request
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	received []webhookBody
+	invalid  int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	var timestamp int64
+	var signature string
+	fmt.Sscanf(strings.Replace(req.Header.Get(WebhookSignatureHeader), ",v1=", " ", 1), "t=%d %s", &timestamp, &signature)
+	if signature != SignWebhook(r.secret, timestamp, body) {
+		r.invalid++
+	}
+
+	var received webhookBody
+	json.Unmarshal(body, &received)
+	r.received = append(r.received, received)
+	w.WriteHeader(r.status)
+}
+
+func TestHttpWebhookHandler(t *testing.T) {
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	repo := NewGormWebhookRepository(db)
+	handler := NewHttpWebhookHandler(core.NewWebhookService(repo))
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// the admin of bank-a manages the webhooks of its tenant
+	app := fiber.New()
+	webhooks := app.Group("/admin/webhooks", func(c *fiber.Ctx) error {
+		setPrincipal(c, &core.Principal{Subject: "admin-1", TenantID: "bank-a", Roles: []string{"admin"}})
+		return c.Next()
+	})
+	webhooks.Post("/", handler.CreateSubscriptionHandler)
+	webhooks.Get("/", handler.GetAllSubscriptionsHandler)
+	webhooks.Delete("/:id", handler.DeleteSubscriptionHandler)
+	webhooks.Get("/dead-letters", handler.GetDeadLettersHandler)
+	webhooks.Post("/deliveries/:id/redeliver", handler.RedeliverHandler)
+	send := func(method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		responseBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, responseBody
+	}
+
+	publisher := core.NewWebhookPublisher(repo)
+	// the partner has a public host name, the client connects to the receiver whatever the url says
+	partnerURL := "http://partner.example.com/hooks"
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	dispatcher := core.NewWebhookDispatcher(repo, NewHTTPWebhookSender(&http.Client{Transport: transport}), core.WebhookDispatcherConfig{
+		MinBackoff: time.Nanosecond, MaxBackoff: time.Nanosecond, MaxAttempts: 2,
+	})
+	message := core.OutboxMessage{
+		ID: 7, TenantID: "bank-a", EventType: core.EventCustomerCreated,
+		Payload: []byte(`{"type":"CustomerCreated","customer_id":1}`), OccurredAt: time.Now().UTC(),
+	}
+
+	// Success case
+	t.Run("successful subscribe", func(t *testing.T) {
+		status, body := send("POST", "/admin/webhooks", fmt.Sprintf(`{"url": %q, "event_types": ["CustomerCreated"]}`, partnerURL))
+		assert.Equal(t, fiber.StatusCreated, status)
+
+		var response map[string]interface{}
+		json.Unmarshal(body, &response)
+		assert.Equal(t, float64(1), response["id"])
+		assert.True(t, strings.HasPrefix(response["secret"].(string), "whsec_"))
+		receiver.secret = response["secret"].(string)
+
+		// the secret is not listed
+		status, body = send("GET", "/admin/webhooks", "")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.NotContains(t, string(body), receiver.secret)
+	})
+
+	t.Run("successful delivery after redelivery of a dead letter", func(t *testing.T) {
+		// the relay publishes the message twice, at-least-once, and events of other types or tenants
+		require.NoError(t, publisher.Publish(context.Background(), message))
+		require.NoError(t, publisher.Publish(context.Background(), message))
+		require.NoError(t, publisher.Publish(context.Background(), core.OutboxMessage{ID: 8, TenantID: "bank-a", EventType: core.EventCustomerDeleted, Payload: []byte("{}")}))
+		require.NoError(t, publisher.Publish(context.Background(), core.OutboxMessage{ID: 9, TenantID: "bank-b", EventType: core.EventCustomerCreated, Payload: []byte("{}")}))
+
+		// the receiver fails both attempts, so the delivery is dead
+		for i := 0; i < 2; i++ {
+			dispatched, err := dispatcher.DispatchOnce(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 1, dispatched)
+		}
+		dispatched, _ := dispatcher.DispatchOnce(context.Background())
+		assert.Equal(t, 0, dispatched)
+
+		status, body := send("GET", "/admin/webhooks/dead-letters", "")
+		assert.Equal(t, fiber.StatusOK, status)
+		var deadLetters []core.WebhookDelivery
+		json.Unmarshal(body, &deadLetters)
+		require.Len(t, deadLetters, 1)
+		assert.Equal(t, 2, deadLetters[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, deadLetters[0].LastStatusCode)
+
+		// redeliver once the receiver is fixed
+		receiver.status = http.StatusOK
+		status, _ = send("POST", fmt.Sprintf("/admin/webhooks/deliveries/%d/redeliver", deadLetters[0].ID), "")
+		assert.Equal(t, fiber.StatusAccepted, status)
+		dispatched, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, dispatched)
+
+		assert.Len(t, receiver.received, 3)
+		assert.Equal(t, 0, receiver.invalid)
+		assert.Equal(t, uint(7), receiver.received[2].ID)
+		assert.Equal(t, core.EventCustomerCreated, receiver.received[2].Type)
+		assert.JSONEq(t, string(message.Payload), string(receiver.received[2].Event))
+
+		status, body = send("GET", "/admin/webhooks/dead-letters", "")
+		assert.Equal(t, "[]", string(body))
+	})
+
+	// Failure case
+	t.Run("(fail) invalid subscription", func(t *testing.T) {
+		status, _ := send("POST", "/admin/webhooks", `{"url": "ftp://example.com", "event_types": ["CustomerCreated"]}`)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+
+		status, _ = send("POST", "/admin/webhooks", fmt.Sprintf(`{"url": %q, "event_types": ["CustomerRenamed"]}`, partnerURL))
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("(fail) subscription to our own network", func(t *testing.T) {
+		status, body := send("POST", "/admin/webhooks", fmt.Sprintf(`{"url": %q, "event_types": ["CustomerCreated"]}`, server.URL))
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		assert.Contains(t, string(body), core.ErrWebhookAddressForbidden.Error())
+	})
+
+	t.Run("(fail) redeliver a delivery that is not dead", func(t *testing.T) {
+		status, _ := send("POST", "/admin/webhooks/deliveries/1/redeliver", "")
+		assert.Equal(t, fiber.StatusConflict, status)
+
+		status, _ = send("POST", "/admin/webhooks/deliveries/99/redeliver", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("(fail) delete twice", func(t *testing.T) {
+		status, _ := send("DELETE", "/admin/webhooks/1", "")
+		assert.Equal(t, fiber.StatusOK, status)
+
+		status, _ = send("DELETE", "/admin/webhooks/1", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+}
+
+func TestHTTPWebhookSender(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	delivery := &core.WebhookDelivery{MessageID: 1, EventType: core.EventCustomerCreated, Payload: []byte("{}")}
+
+	// Failure case
+	t.Run("(fail) internal address at send time", func(t *testing.T) {
+		// the host name of the subscription resolves to the loopback address when the delivery is sent
+		host := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+		sender := NewHTTPWebhookSender(nil)
+
+		for _, url := range []string{server.URL, host} {
+			_, err := sender.Send(context.Background(), &core.WebhookSubscription{URL: url, Secret: "whsec_test"}, delivery)
+			assert.ErrorIs(t, err, core.ErrWebhookAddressForbidden, url)
+		}
+		assert.Empty(t, receiver.received)
+	})
+}
@@ -0,0 +1,217 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (idempotency_middleware.go)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// IdempotencyRecord is the fingerprint of the first request of a key and, once completed, its response
+type IdempotencyRecord struct {
+	Key         string `gorm:"column:idempotency_key;primaryKey"`
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+// IdempotencyStore keeps the records until they expire
+type IdempotencyStore interface {
+	// Begin reserves the key for a new request, created is false when a live record already exists and it is returned
+	Begin(ctx context.Context, key string, fingerprint string, expiresAt time.Time) (record *IdempotencyRecord, created bool, err error)
+	// Complete stores the response of the reserved key, it is replayed until expiresAt
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error
+	// Release removes the reservation, so the request can be retried
+	Release(ctx context.Context, key string) error
+	// DeleteExpired removes every record expired at now
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// MemoryIdempotencyStore keeps the records of a single instance in memory
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*IdempotencyRecord
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]*IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string, expiresAt time.Time) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok && time.Now().Before(record.ExpiresAt) {
+		copied := *record
+		return &copied, false, nil
+	}
+
+	record := &IdempotencyRecord{Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	s.records[key] = record
+	copied := *record
+	return &copied, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return errors.New("idempotency key not reserved")
+	}
+	record.Completed = true
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = body
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, record := range s.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CleanupIdempotencyRecords deletes the expired records of store every interval until ctx is canceled, otherwise
+// only the keys sent again replace their expired record
+func CleanupIdempotencyRecords(ctx context.Context, store IdempotencyStore, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		// a failed cleanup is retried on the next interval
+		store.DeleteExpired(ctx, time.Now())
+	}
+}
+
+// IdempotencyConfig configures the idempotency middleware, TTL is how long responses are replayed and LockTimeout
+// how long a key stays reserved by a request that never completed, e.g. because the instance crashed
+type IdempotencyConfig struct {
+	Store       IdempotencyStore
+	TTL         time.Duration
+	LockTimeout time.Duration
+}
+
+// NewIdempotencyMiddleware replays the stored response when a request is retried with the same Idempotency-Key.
+// Keys are scoped to the tenant and principal, a key reused with a different request is rejected with 422 and
+// a retry while the first request still runs gets 409 until the lock timeout. Server errors are not stored, so
+// they can be retried. Requests without the header are passed on untouched
+func NewIdempotencyMiddleware(config IdempotencyConfig) fiber.Handler {
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = time.Minute
+	}
+
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			return c.Next()
+		}
+		if len(idempotencyKey) > 255 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid idempotency key"})
+		}
+
+		ctx := c.UserContext()
+		key := idempotencyScope(ctx) + ":" + idempotencyKey
+		fingerprint := requestFingerprint(c)
+
+		// call Begin() to reserve the key in store until the lock timeout and check Error
+		record, created, err := config.Store.Begin(ctx, key, fingerprint, time.Now().Add(config.LockTimeout))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if !created {
+			switch {
+			case record.Fingerprint != fingerprint:
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": ErrIdempotencyKeyReused.Error()})
+			case !record.Completed:
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": ErrIdempotencyKeyInProgress.Error()})
+			}
+
+			// replay the stored response
+			c.Set("Idempotent-Replayed", "true")
+			c.Set(fiber.HeaderContentType, record.ContentType)
+			return c.Status(record.StatusCode).Send(record.Body)
+		}
+
+		// run the request and store its response, unless it failed on the server side
+		if err := c.Next(); err != nil {
+			config.Store.Release(ctx, key)
+			return err
+		}
+		statusCode := c.Response().StatusCode()
+		if statusCode >= fiber.StatusInternalServerError {
+			return config.Store.Release(ctx, key)
+		}
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := config.Store.Complete(ctx, key, statusCode, contentType, body, time.Now().Add(config.TTL)); err != nil {
+			// the request succeeded, so its response is sent anyway. The key is released, when that fails as well
+			// the reservation ends with the lock timeout
+			core.LoggerFromContext(ctx).ErrorContext(ctx, "idempotency key not completed", "error", err)
+			config.Store.Release(ctx, key)
+		}
+		return nil
+	}
+}
+
+// idempotencyScope keeps the keys of different tenants and clients apart
+func idempotencyScope(ctx context.Context) string {
+	subject := ""
+	if principal, ok := core.PrincipalFromContext(ctx); ok {
+		subject = principal.Subject
+	}
+	return core.TenantFromContext(ctx) + ":" + subject
+}
+
+// requestFingerprint identifies the request by method, path and body
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
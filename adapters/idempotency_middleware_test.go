@@ -0,0 +1,212 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	db := setupTestDB()
+	defer func() {
+		// close the shared in-memory database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	stores := map[string]func() IdempotencyStore{
+		"memory": func() IdempotencyStore { return NewMemoryIdempotencyStore() },
+		"gorm": func() IdempotencyStore {
+			db.Where("1 = 1").Delete(&IdempotencyRecord{})
+			return NewGormIdempotencyStore(db)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testIdempotencyMiddleware(t, newStore)
+		})
+	}
+}
+
+func testIdempotencyMiddleware(t *testing.T, newStore func() IdempotencyStore) {
+	var calls int
+	var status int
+	setupAppWithLock := func(store IdempotencyStore, lockTimeout time.Duration) *fiber.App {
+		calls = 0
+		status = fiber.StatusCreated
+		app := fiber.New()
+		// authenticate requests with the X-API-Key header as the given subject
+		app.Use(func(c *fiber.Ctx) error {
+			setPrincipal(c, &core.Principal{Subject: c.Get(ApiKeyHeader), TenantID: "bank-a"})
+			return c.Next()
+		})
+		app.Post("/customers", NewIdempotencyMiddleware(IdempotencyConfig{Store: store, TTL: time.Hour, LockTimeout: lockTimeout}), func(c *fiber.Ctx) error {
+			calls++
+			return c.Status(status).SendString("Created successfully!")
+		})
+		return app
+	}
+	setupApp := func(store IdempotencyStore) *fiber.App {
+		return setupAppWithLock(store, time.Minute)
+	}
+	send := func(app *fiber.App, key string, subject string, body string) *http.Response {
+		req := httptest.NewRequest("POST", "/customers", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(ApiKeyHeader, subject)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Success case
+	t.Run("successful replay of a retried request", func(t *testing.T) {
+		app := setupApp(newStore())
+
+		first := send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+		retry := send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+
+		assert.Equal(t, fiber.StatusCreated, first.StatusCode)
+		assert.Equal(t, fiber.StatusCreated, retry.StatusCode)
+		assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+		body, _ := io.ReadAll(retry.Body)
+		assert.Equal(t, "Created successfully!", string(body))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("successful without idempotency key", func(t *testing.T) {
+		app := setupApp(newStore())
+
+		send(app, "", "client", `{"name": "Fiat", "age": 24}`)
+		send(app, "", "client", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("successful with the same key of another client", func(t *testing.T) {
+		app := setupApp(newStore())
+
+		send(app, "key-1", "client-a", `{"name": "Fiat", "age": 24}`)
+		resp := send(app, "key-1", "client-b", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, "", resp.Header.Get("Idempotent-Replayed"))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("successful retry after a server error", func(t *testing.T) {
+		app := setupApp(newStore())
+		status = fiber.StatusInternalServerError
+		send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+
+		status = fiber.StatusCreated
+		resp := send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("successful replay after the lock timeout", func(t *testing.T) {
+		app := setupAppWithLock(newStore(), 50*time.Millisecond)
+		send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+
+		// the completed response is kept for the TTL, not for the lock timeout
+		time.Sleep(100 * time.Millisecond)
+		resp := send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("successful retry of a request that never completed", func(t *testing.T) {
+		store := newStore()
+		app := setupAppWithLock(store, time.Minute)
+		// a first request reserved the key and its instance crashed
+		body := `{"name": "Fiat", "age": 24}`
+		store.Begin(context.Background(), "bank-a:client:key-1", fingerprintOf(t, body), time.Now().Add(50*time.Millisecond))
+
+		time.Sleep(100 * time.Millisecond)
+		resp := send(app, "key-1", "client", body)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("successful response when completing the key fails", func(t *testing.T) {
+		store := &failingCompleteStore{IdempotencyStore: newStore()}
+		app := setupApp(store)
+
+		resp := send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		// the key is released, so the retry is no conflict
+		resp = send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, 2, calls)
+	})
+
+	// Failure case
+	t.Run("(fail) key reused with a different body", func(t *testing.T) {
+		app := setupApp(newStore())
+		send(app, "key-1", "client", `{"name": "Fiat", "age": 24}`)
+
+		resp := send(app, "key-1", "client", `{"name": "Anfat", "age": 40}`)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("(fail) request still in progress", func(t *testing.T) {
+		store := newStore()
+		app := setupApp(store)
+		// reserve the key as a concurrent first request would
+		body := `{"name": "Fiat", "age": 24}`
+		store.Begin(context.Background(), "bank-a:client:key-1", fingerprintOf(t, body), time.Now().Add(time.Hour))
+
+		resp := send(app, "key-1", "client", body)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("successful delete of expired records", func(t *testing.T) {
+		store := newStore()
+		store.Begin(context.Background(), "expired", "fingerprint", time.Now().Add(-time.Minute))
+		store.Begin(context.Background(), "live", "fingerprint", time.Now().Add(time.Hour))
+
+		deleted, err := store.DeleteExpired(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		// an expired key can be reserved again
+		_, created, err := store.Begin(context.Background(), "expired", "other", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, created)
+	})
+}
+
+// failingCompleteStore fails to store every response, as a store losing its database would
+type failingCompleteStore struct {
+	IdempotencyStore
+}
+
+func (s *failingCompleteStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	return errors.New("database is closed")
+}
+
+// fingerprintOf computes the fingerprint the middleware uses for a POST /customers request
+func fingerprintOf(t *testing.T, body string) string {
+	var fingerprint string
+	app := fiber.New()
+	app.Post("/customers", func(c *fiber.Ctx) error {
+		fingerprint = requestFingerprint(c)
+		return nil
+	})
+	_, err := app.Test(httptest.NewRequest("POST", "/customers", bytes.NewBufferString(body)))
+	require.NoError(t, err)
+	return fingerprint
+}
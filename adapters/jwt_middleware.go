@@ -0,0 +1,219 @@
+package adapters
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ! Primary adapter (jwt_middleware.go)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+const (
+	bearerChallenge             = `Bearer`
+	bearerInvalidTokenChallenge = `Bearer error="invalid_token"`
+)
+
+// JSONWebKey is a single key of a JWKS document, only the fields used for HS256 (oct) and RS256 (RSA) are read
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet holds the verification keys of a JWKS document indexed by kid
+type KeySet struct {
+	keys map[string]verificationKey
+}
+
+// LoadJWKS reads a JWKS document from a local file
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJWKS(data)
+}
+
+// ParseJWKS decodes a JWKS document, keys with kty "oct" are used for HS256 and keys with kty "RSA" for RS256
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var document jsonWebKeySet
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	keySet := &KeySet{keys: make(map[string]verificationKey, len(document.Keys))}
+	for _, jwk := range document.Keys {
+		// skip keys that are not meant for signature verification
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.verificationKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks key %q: %w", jwk.Kid, err)
+		}
+		if _, exists := keySet.keys[jwk.Kid]; exists {
+			return nil, fmt.Errorf("invalid jwks: duplicate kid %q", jwk.Kid)
+		}
+		keySet.keys[jwk.Kid] = key
+	}
+
+	if len(keySet.keys) == 0 {
+		return nil, errors.New("invalid jwks: no signing keys")
+	}
+
+	return keySet, nil
+}
+
+func (jwk JSONWebKey) verificationKey() (verificationKey, error) {
+	switch jwk.Kty {
+	case "oct":
+		if jwk.Alg != "" && jwk.Alg != jwt.SigningMethodHS256.Alg() {
+			return verificationKey{}, fmt.Errorf("unsupported alg %q", jwk.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil || len(secret) == 0 {
+			return verificationKey{}, errors.New("invalid k")
+		}
+		return verificationKey{alg: jwt.SigningMethodHS256.Alg(), key: secret}, nil
+
+	case "RSA":
+		if jwk.Alg != "" && jwk.Alg != jwt.SigningMethodRS256.Alg() {
+			return verificationKey{}, fmt.Errorf("unsupported alg %q", jwk.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil || len(n) == 0 {
+			return verificationKey{}, errors.New("invalid n")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 {
+			return verificationKey{}, errors.New("invalid e")
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+			return verificationKey{}, errors.New("invalid e")
+		}
+		publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		return verificationKey{alg: jwt.SigningMethodRS256.Alg(), key: publicKey}, nil
+	}
+
+	return verificationKey{}, fmt.Errorf("unsupported kty %q", jwk.Kty)
+}
+
+// lookup finds the key for the token header, a token without kid is only accepted when the set has a single key
+func (s *KeySet) lookup(token *jwt.Token) (interface{}, error) {
+	var key verificationKey
+	if kid, ok := token.Header["kid"].(string); ok {
+		found, exists := s.keys[kid]
+		if !exists {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		key = found
+	} else {
+		if len(s.keys) != 1 {
+			return nil, errors.New("missing kid")
+		}
+		for _, only := range s.keys {
+			key = only
+		}
+	}
+
+	// the alg of the token must match the key type, so an RSA public key can never be used as an HMAC secret
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("alg %q does not match key", token.Method.Alg())
+	}
+
+	return key.key, nil
+}
+
+// JWTConfig configures the JWT middleware, Issuer and Audience are only checked when set
+type JWTConfig struct {
+	KeySet   *KeySet
+	Issuer   string
+	Audience string
+}
+
+type principalClaims struct {
+	TenantID string   `json:"tenant_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewJWTMiddleware authenticates "Authorization: Bearer" tokens and puts the principal into the user context
+func NewJWTMiddleware(config JWTConfig) fiber.Handler {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	parser := jwt.NewParser(options...)
+
+	return func(c *fiber.Ctx) error {
+		// another authenticator (e.g. api key) has already authenticated the request
+		if _, ok := c.Locals(principalLocalsKey).(*core.Principal); ok {
+			return c.Next()
+		}
+
+		// get the token from Authorization header and check Error
+		header := c.Get(fiber.HeaderAuthorization)
+		scheme, tokenString, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(tokenString) == "" {
+			return unauthorized(c, bearerChallenge, ErrMissingToken)
+		}
+
+		// verify signature and registered claims and check Error
+		var claims principalClaims
+		if _, err := parser.ParseWithClaims(strings.TrimSpace(tokenString), &claims, config.KeySet.lookup); err != nil {
+			return unauthorized(c, bearerInvalidTokenChallenge, ErrInvalidToken)
+		}
+		// a token without tenant must not fall back to the default tenant that holds the CLI and imported data
+		if claims.Subject == "" || claims.TenantID == "" {
+			return unauthorized(c, bearerInvalidTokenChallenge, ErrInvalidToken)
+		}
+
+		principal := &core.Principal{
+			Subject:    claims.Subject,
+			TenantID:   claims.TenantID,
+			Roles:      claims.Roles,
+			Scopes:     strings.Fields(claims.Scope),
+			AuthMethod: core.AuthMethodJWT,
+		}
+		setPrincipal(c, principal)
+
+		return c.Next()
+	}
+}
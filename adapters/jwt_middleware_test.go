@@ -0,0 +1,215 @@
+package adapters
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeys are generated locally for every test run
+type testKeys struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PrivateKey
+	jwksPath   string
+}
+
+func setupTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	hmacSecret := make([]byte, 32)
+	_, err = rand.Read(hmacSecret)
+	require.NoError(t, err)
+
+	// write the JWKS document of both keys into a temporary file
+	document := jsonWebKeySet{Keys: []JSONWebKey{
+		{Kty: "oct", Kid: "hmac-key", Alg: "HS256", Use: "sig", K: base64.RawURLEncoding.EncodeToString(hmacSecret)},
+		{
+			Kty: "RSA", Kid: "rsa-key", Alg: "RS256", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+	}}
+	data, err := json.Marshal(document)
+	require.NoError(t, err)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksPath, data, 0o600))
+
+	return testKeys{hmacSecret: hmacSecret, rsaKey: rsaKey, jwksPath: jwksPath}
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":       "user-1",
+		"tenant_id": "bank-a",
+		"roles":     []string{"operator"},
+		"scope":     "customers:read customers:write",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// setupJWTTestApp returns an app whose only route answers with the principal read back from the user context
+func setupJWTTestApp(t *testing.T, keys testKeys) *fiber.App {
+	keySet, err := LoadJWKS(keys.jwksPath)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(NewJWTMiddleware(JWTConfig{KeySet: keySet}))
+	app.Get("/whoami", func(c *fiber.Ctx) error {
+		principal, ok := core.PrincipalFromContext(c.UserContext())
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.JSON(principal)
+	})
+	return app
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	keys := setupTestKeys(t)
+	app := setupJWTTestApp(t, keys)
+
+	send := func(token string) (int, core.Principal, string) {
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		var principal core.Principal
+		if resp.StatusCode == fiber.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&principal))
+		}
+		return resp.StatusCode, principal, resp.Header.Get("WWW-Authenticate")
+	}
+
+	// Success case
+	t.Run("successful HS256 token", func(t *testing.T) {
+		token := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, validClaims())
+
+		status, principal, _ := send(token)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "user-1", principal.Subject)
+		assert.Equal(t, "bank-a", principal.TenantID)
+		assert.Equal(t, []string{"operator"}, principal.Roles)
+		assert.Equal(t, []string{"customers:read", "customers:write"}, principal.Scopes)
+		assert.Equal(t, core.AuthMethodJWT, principal.AuthMethod)
+	})
+
+	t.Run("successful RS256 token", func(t *testing.T) {
+		token := signTestToken(t, jwt.SigningMethodRS256, "rsa-key", keys.rsaKey, validClaims())
+
+		status, principal, _ := send(token)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "user-1", principal.Subject)
+	})
+
+	// Failure case
+	t.Run("(fail) missing token", func(t *testing.T) {
+		status, _, challenge := send("")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, "Bearer", challenge)
+	})
+
+	t.Run("(fail) expired token", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		token := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, claims)
+
+		status, _, challenge := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		assert.Equal(t, `Bearer error="invalid_token"`, challenge)
+	})
+
+	t.Run("(fail) token without exp", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "exp")
+		token := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, claims)
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("(fail) signed with another key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := signTestToken(t, jwt.SigningMethodRS256, "rsa-key", otherKey, validClaims())
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("(fail) unknown kid", func(t *testing.T) {
+		token := signTestToken(t, jwt.SigningMethodHS256, "other-key", keys.hmacSecret, validClaims())
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("(fail) HS256 token signed with the RSA public key", func(t *testing.T) {
+		// alg confusion: the public key must never be accepted as an HMAC secret
+		publicKey := keys.rsaKey.PublicKey.N.Bytes()
+		token := signTestToken(t, jwt.SigningMethodHS256, "rsa-key", publicKey, validClaims())
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("(fail) token without subject", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "sub")
+		token := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, claims)
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+
+	t.Run("(fail) token without tenant", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "tenant_id")
+		token := signTestToken(t, jwt.SigningMethodHS256, "hmac-key", keys.hmacSecret, claims)
+
+		status, _, _ := send(token)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("(fail) invalid json", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{`))
+		assert.Error(t, err)
+	})
+
+	t.Run("(fail) no signing keys", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys": [{"kty": "oct", "kid": "enc", "use": "enc", "k": "c2VjcmV0"}]}`))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no signing keys")
+	})
+
+	t.Run("(fail) unsupported key type", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys": [{"kty": "EC", "kid": "ec"}]}`))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported kty")
+	})
+}
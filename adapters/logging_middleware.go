@@ -0,0 +1,105 @@
+package adapters
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ! Primary adapter (logging_middleware.go)
+
+// HeaderRequestID correlates the logs of a request, the id of the caller is kept or a new one is generated
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength keeps ids of callers from flooding the logs
+const maxRequestIDLength = 128
+
+// NewRequestIDMiddleware puts the logger of the request into the user context, it carries the request id and the
+// trace id of the request span. The id is returned in X-Request-ID, so callers can quote it. It runs after the
+// tracing middleware and before every middleware that logs
+func NewRequestIDMiddleware(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestId := c.Get(HeaderRequestID)
+		if !validRequestID(requestId) {
+			requestId = newRequestID()
+		}
+		c.Set(HeaderRequestID, requestId)
+
+		requestLogger := logger.With("request_id", requestId)
+		if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.HasTraceID() {
+			requestLogger = requestLogger.With("trace_id", spanContext.TraceID().String())
+		}
+		c.SetUserContext(core.WithLogger(c.UserContext(), requestLogger))
+
+		return c.Next()
+	}
+}
+
+// NewAccessLogMiddleware logs every request with its status and latency once it is answered, server errors at
+// error level
+func NewAccessLogMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.Int("bytes", len(c.Response().Body())),
+			slog.String("ip", c.IP()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		ctx := c.UserContext()
+		core.LoggerFromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+		return err
+	}
+}
+
+// responseStatus is the status of the response, also when the handler returned an error: the error handler writes
+// its status only after every middleware returned
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// validRequestID accepts the printable ASCII ids of callers up to maxRequestIDLength
+func validRequestID(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIDLength {
+		return false
+	}
+	for _, char := range requestId {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
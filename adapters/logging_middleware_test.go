@@ -0,0 +1,165 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// logLines decodes the JSON lines written by slog
+func logLines(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &decoded))
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	app := fiber.New()
+	app.Use(NewRequestIDMiddleware(logger), NewAccessLogMiddleware())
+	app.Get("/customers/:id", func(c *fiber.Ctx) error {
+		core.LoggerFromContext(c.UserContext()).Info("in handler")
+		if c.Params("id") == "0" {
+			return fiber.ErrInternalServerError
+		}
+		return c.SendString("ok")
+	})
+
+	// Success case
+	t.Run("successful request id of the caller", func(t *testing.T) {
+		out.Reset()
+		req := httptest.NewRequest("GET", "/customers/1", nil)
+		req.Header.Set(HeaderRequestID, "req-123")
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "req-123", resp.Header.Get(HeaderRequestID))
+
+		// the logs of the handler and the access log carry the request id
+		lines := logLines(t, &out)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "in handler", lines[0]["msg"])
+		assert.Equal(t, "req-123", lines[0]["request_id"])
+		assert.Equal(t, "request", lines[1]["msg"])
+		assert.Equal(t, "INFO", lines[1]["level"])
+		assert.Equal(t, "req-123", lines[1]["request_id"])
+		assert.Equal(t, "/customers/:id", lines[1]["route"])
+		assert.Equal(t, float64(fiber.StatusOK), lines[1]["status"])
+		assert.Contains(t, lines[1], "latency_ms")
+	})
+
+	t.Run("successful generated request id", func(t *testing.T) {
+		out.Reset()
+		req := httptest.NewRequest("GET", "/customers/1", nil)
+		// ids with spaces or control characters are replaced
+		req.Header.Set(HeaderRequestID, "bad id")
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		requestId := resp.Header.Get(HeaderRequestID)
+		assert.Len(t, requestId, 32)
+		assert.Equal(t, requestId, logLines(t, &out)[1]["request_id"])
+	})
+
+	// Failure case
+	t.Run("(fail) server error logged as error", func(t *testing.T) {
+		out.Reset()
+		resp, err := app.Test(httptest.NewRequest("GET", "/customers/0", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+		access := logLines(t, &out)[1]
+		assert.Equal(t, "ERROR", access["level"])
+		assert.Equal(t, float64(fiber.StatusInternalServerError), access["status"])
+		assert.Equal(t, "Internal Server Error", access["error"])
+	})
+}
+
+func TestGormLogger(t *testing.T) {
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	var out bytes.Buffer
+	ctx := core.WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// Success case
+	t.Run("successful statement at debug without values", func(t *testing.T) {
+		out.Reset()
+		db.Logger = NewGormLogger(time.Minute)
+		assert.NoError(t, NewGormCustomerRepository(db).Save(ctx, &core.Customer{Name: "Secret Name", Age: 30}))
+
+		lines := logLines(t, &out)
+		require.NotEmpty(t, lines)
+		for _, line := range lines {
+			assert.Equal(t, "DEBUG", line["level"])
+			assert.NotContains(t, line["sql"], "Secret Name")
+		}
+	})
+
+	t.Run("successful silent mode", func(t *testing.T) {
+		out.Reset()
+		db.Logger = NewGormLogger(time.Minute).LogMode(gormlogger.Silent)
+		_, err := NewGormCustomerRepository(db).GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("successful statement not rendered above debug", func(t *testing.T) {
+		out.Reset()
+		infoCtx := core.WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo})))
+		rendered := false
+
+		// Trace() for a statement logged at debug and check the SQL is not rendered
+		NewGormLogger(time.Minute).Trace(infoCtx, time.Now(), func() (string, int64) {
+			rendered = true
+			return "SELECT 1", 1
+		}, nil)
+		assert.False(t, rendered)
+		assert.Empty(t, out.String())
+	})
+
+	// Failure case
+	t.Run("(fail) slow query as warning", func(t *testing.T) {
+		out.Reset()
+		db.Logger = NewGormLogger(time.Nanosecond)
+		_, err := NewGormCustomerRepository(db).GetAll(ctx)
+		assert.NoError(t, err)
+
+		line := logLines(t, &out)[0]
+		assert.Equal(t, "WARN", line["level"])
+		assert.Equal(t, "slow query", line["msg"])
+	})
+
+	t.Run("(fail) failed query as error", func(t *testing.T) {
+		out.Reset()
+		db.Logger = NewGormLogger(time.Minute)
+		assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error)
+
+		line := logLines(t, &out)[0]
+		assert.Equal(t, "ERROR", line["level"])
+		assert.Equal(t, "query failed", line["msg"])
+		assert.Contains(t, line["error"], "missing_table")
+	})
+}
@@ -0,0 +1,111 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// * Secondary adapter (metrics_customer_repository.go)
+
+// MetricsCustomerRepository decorates a CustomerRepository with the duration and the errors of every method
+type MetricsCustomerRepository struct {
+	r        core.CustomerRepository
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// NewCustomerRepositoryMetrics registers the metrics once and returns a decorator that records them, for the
+// repositories of the unit of work as well
+func NewCustomerRepositoryMetrics(registerer prometheus.Registerer) CustomerRepositoryDecorator {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "customer_repository_duration_seconds",
+		Help:    "Duration of the customer repository methods.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	errs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "customer_repository_errors_total",
+		Help: "Errors of the customer repository methods, a missing customer is no error.",
+	}, []string{"method"})
+	registerer.MustRegister(duration, errs)
+
+	return func(r core.CustomerRepository) core.CustomerRepository {
+		return &MetricsCustomerRepository{r: r, duration: duration, errors: errs}
+	}
+}
+
+// observe records one call of method that started at started and returns its err
+func (r *MetricsCustomerRepository) observe(method string, started time.Time, err error) error {
+	r.duration.WithLabelValues(method).Observe(time.Since(started).Seconds())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		r.errors.WithLabelValues(method).Inc()
+	}
+	return err
+}
+
+func (r *MetricsCustomerRepository) Save(ctx context.Context, customer *core.Customer) error {
+	started := time.Now()
+	return r.observe("Save", started, r.r.Save(ctx, customer))
+}
+
+func (r *MetricsCustomerRepository) Get(ctx context.Context, customerId uint) (*core.Customer, error) {
+	started := time.Now()
+	customer, err := r.r.Get(ctx, customerId)
+	return customer, r.observe("Get", started, err)
+}
+
+func (r *MetricsCustomerRepository) GetAll(ctx context.Context) ([]core.Customer, error) {
+	started := time.Now()
+	customers, err := r.r.GetAll(ctx)
+	return customers, r.observe("GetAll", started, err)
+}
+
+func (r *MetricsCustomerRepository) Update(ctx context.Context, customerId uint, customer *core.Customer) (*core.Customer, error) {
+	started := time.Now()
+	updated, err := r.r.Update(ctx, customerId, customer)
+	return updated, r.observe("Update", started, err)
+}
+
+func (r *MetricsCustomerRepository) Delete(ctx context.Context, customerId uint) error {
+	started := time.Now()
+	return r.observe("Delete", started, r.r.Delete(ctx, customerId))
+}
+
+func (r *MetricsCustomerRepository) Search(ctx context.Context, customerId uint) error {
+	started := time.Now()
+	return r.observe("Search", started, r.r.Search(ctx, customerId))
+}
+
+// ForEachBatch is observed as a whole, the time spent in fn included
+func (r *MetricsCustomerRepository) ForEachBatch(ctx context.Context, batchSize int, fn func(customers []core.Customer) error) error {
+	started := time.Now()
+	return r.observe("ForEachBatch", started, r.r.ForEachBatch(ctx, batchSize, fn))
+}
+
+func (r *MetricsCustomerRepository) ApplyBatch(ctx context.Context, operations []core.BatchOperation) ([]uint, error) {
+	started := time.Now()
+	customerIds, err := r.r.ApplyBatch(ctx, operations)
+	return customerIds, r.observe("ApplyBatch", started, err)
+}
+
+func (r *MetricsCustomerRepository) Changes(ctx context.Context, since uint, limit int) ([]core.ChangeRecord, error) {
+	started := time.Now()
+	changes, err := r.r.Changes(ctx, since, limit)
+	return changes, r.observe("Changes", started, err)
+}
+
+func (r *MetricsCustomerRepository) ChangeHead(ctx context.Context) (uint, error) {
+	started := time.Now()
+	head, err := r.r.ChangeHead(ctx)
+	return head, r.observe("ChangeHead", started, err)
+}
+
+func (r *MetricsCustomerRepository) ExistingNames(ctx context.Context, names []string) ([]string, error) {
+	started := time.Now()
+	existing, err := r.r.ExistingNames(ctx, names)
+	return existing, r.observe("ExistingNames", started, err)
+}
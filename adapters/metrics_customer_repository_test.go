@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsCustomerRepository(t *testing.T) {
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	registry := prometheus.NewRegistry()
+	repo := NewCustomerRepositoryMetrics(registry)(NewGormCustomerRepository(db))
+	registry.MustRegister(NewCustomerCountCollector(db))
+	ctx := context.Background()
+
+	// Success case
+	t.Run("successful durations and customer count", func(t *testing.T) {
+		assert.NoError(t, repo.Save(ctx, &core.Customer{Name: "Alice", Age: 30}))
+		assert.NoError(t, repo.Save(core.WithTenant(ctx, "other"), &core.Customer{Name: "Bob", Age: 40}))
+		_, err := repo.GetAll(ctx)
+		assert.NoError(t, err)
+
+		expected := `
+# HELP customers_total Customers stored per tenant.
+# TYPE customers_total gauge
+customers_total{tenant=""} 1
+customers_total{tenant="other"} 1
+`
+		assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "customers_total"))
+		assert.Equal(t, 2, testutil.CollectAndCount(registry, "customer_repository_duration_seconds"))
+	})
+
+	t.Run("successful writes of the service through the unit of work", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		metrics := NewCustomerRepositoryMetrics(registry)
+		service := core.NewCustomerService(metrics(NewGormCustomerRepository(db)), NewGormUnitOfWork(db, metrics))
+
+		// CreateCustomer() and DeleteCustomer() write in a transaction and check the methods observed
+		assert.NoError(t, service.CreateCustomer(ctx, core.Customer{Name: "Carol", Age: 25}))
+		customers, err := service.GetAllCustomer(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, service.DeleteCustomer(ctx, customers[len(customers)-1].ID))
+
+		var methods []string
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "customer_repository_duration_seconds" {
+				for _, metric := range family.GetMetric() {
+					methods = append(methods, metric.GetLabel()[0].GetValue())
+				}
+			}
+		}
+		assert.Equal(t, []string{"Delete", "GetAll", "Save"}, methods)
+	})
+
+	// Failure case
+	t.Run("(fail) errors without missing customers", func(t *testing.T) {
+		// a missing customer is no error of the repository
+		_, err := repo.Get(ctx, 999)
+		assert.Error(t, err)
+
+		// the name is taken in the tenant
+		assert.Error(t, repo.Save(ctx, &core.Customer{Name: "Alice", Age: 31}))
+
+		expected := `
+# HELP customer_repository_errors_total Errors of the customer repository methods, a missing customer is no error.
+# TYPE customer_repository_errors_total counter
+customer_repository_errors_total{method="Save"} 1
+`
+		assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "customer_repository_errors_total"))
+	})
+}
@@ -0,0 +1,72 @@
+package adapters
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ! Primary adapter (metrics_middleware.go)
+
+// NewMetricsMiddleware counts the requests and observes their latency per method, route and status. The route is
+// the path template ("/customers/:id"), so the labels stay few whatever ids are requested. Requests stopped by a
+// middleware, or matching no route, are counted under the path of that middleware
+func NewMetricsMiddleware(registerer prometheus.Registerer) fiber.Handler {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	registerer.MustRegister(requests, duration)
+
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+
+		labels := prometheus.Labels{"method": c.Method(), "route": c.Route().Path, "status": strconv.Itoa(status)}
+		requests.With(labels).Inc()
+		duration.With(labels).Observe(time.Since(started).Seconds())
+		return err
+	}
+}
+
+// MetricsHandler answers GET /metrics in the Prometheus text format
+func MetricsHandler(gatherer prometheus.Gatherer) fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+}
+
+// NewMetricsServer returns a worker serving GET /metrics on ln until its ctx is canceled. The metrics name every
+// tenant, so they are served apart from the public API on an address only reachable from inside the network
+func NewMetricsServer(ln net.Listener, gatherer prometheus.Gatherer) func(ctx context.Context) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/metrics", MetricsHandler(gatherer))
+
+	return func(ctx context.Context) {
+		served := make(chan struct{})
+		go func() {
+			defer close(served)
+			app.Listener(ln)
+		}()
+
+		select {
+		case <-ctx.Done():
+			// closing the listener also ends a server that has not started serving yet
+			app.Shutdown()
+			ln.Close()
+			<-served
+		case <-served:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	app := fiber.New()
+	app.Use(NewMetricsMiddleware(registry))
+	app.Get("/customers/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return fiber.ErrBadRequest
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/metrics", MetricsHandler(registry))
+
+	for _, path := range []string{"/customers/1", "/customers/2", "/customers/0"} {
+		_, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+	}
+
+	// Success case
+	t.Run("successful count per route and status", func(t *testing.T) {
+		expected := `
+# HELP http_requests_total HTTP requests by method, route and status.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/customers/:id",status="200"} 2
+http_requests_total{method="GET",route="/customers/:id",status="400"} 1
+`
+		assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "http_requests_total"))
+		assert.Equal(t, 2, testutil.CollectAndCount(registry, "http_request_duration_seconds"))
+	})
+
+	t.Run("successful /metrics", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `http_request_duration_seconds_count{method="GET",route="/customers/:id",status="200"} 2`)
+	})
+}
+
+func TestMetricsServer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test."}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewMetricsServer(ln, registry)(ctx)
+	}()
+
+	// Success case
+	t.Run("successful /metrics on its own address", func(t *testing.T) {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), "test_total 0")
+	})
+
+	t.Run("successful stop on cancel", func(t *testing.T) {
+		cancel()
+		<-done
+
+		_, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the role-to-scope mapping, YAML is read so JSON files work as well
+//
+//	roles:
+//	  admin: [customers:read, customers:write, customers:delete, pii:read]
+type policyFile struct {
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// LoadPolicyFile reads the role-to-scope mapping of the authorization policy
+func LoadPolicyFile(path string) (*core.Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, errors.New("invalid policy file: no roles")
+	}
+
+	return core.NewPolicy(file.Roles), nil
+}
@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	// Success case
+	t.Run("successful yaml", func(t *testing.T) {
+		path := writeTestFile(t, "policy.yaml", "roles:\n  auditor: [customers:read]\n")
+
+		policy, err := LoadPolicyFile(path)
+		assert.NoError(t, err)
+		assert.True(t, policy.Allows(&core.Principal{Roles: []string{"auditor"}}, core.PermissionCustomersRead))
+		assert.False(t, policy.Allows(&core.Principal{Roles: []string{"auditor"}}, core.PermissionCustomersDelete))
+	})
+
+	t.Run("successful json", func(t *testing.T) {
+		path := writeTestFile(t, "policy.json", `{"roles": {"admin": ["customers:delete"]}}`)
+
+		policy, err := LoadPolicyFile(path)
+		assert.NoError(t, err)
+		assert.True(t, policy.Allows(&core.Principal{Roles: []string{"admin"}}, core.PermissionCustomersDelete))
+	})
+
+	t.Run("successful repository policy", func(t *testing.T) {
+		_, err := LoadPolicyFile("../policy.yaml")
+		assert.NoError(t, err)
+	})
+
+	// Failure case
+	t.Run("(fail) no roles", func(t *testing.T) {
+		path := writeTestFile(t, "policy.yaml", "roles: {}\n")
+
+		_, err := LoadPolicyFile(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("(fail) missing file", func(t *testing.T) {
+		_, err := LoadPolicyFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,47 @@
+package adapters
+
+import (
+	"errors"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Problem is an RFC 9457 problem details response
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// sendProblem writes a problem details response for the given status
+func sendProblem(c *fiber.Ctx, status int, detail string) error {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    utils.StatusMessage(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.OriginalURL(),
+	}
+	c.Status(status)
+	return c.JSON(problem, MIMEApplicationProblemJSON)
+}
+
+// isAuthorizationError reports whether err comes from the authorization policy of core
+func isAuthorizationError(err error) bool {
+	return errors.Is(err, core.ErrForbidden) || errors.Is(err, core.ErrUnauthenticated)
+}
+
+// authorizationProblem writes 401 or 403 as problem details for an authorization error
+func authorizationProblem(c *fiber.Ctx, err error) error {
+	if errors.Is(err, core.ErrUnauthenticated) {
+		c.Set(fiber.HeaderWWWAuthenticate, bearerChallenge)
+		return sendProblem(c, fiber.StatusUnauthorized, err.Error())
+	}
+	return sendProblem(c, fiber.StatusForbidden, err.Error())
+}
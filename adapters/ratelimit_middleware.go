@@ -0,0 +1,164 @@
+package adapters
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ! Primary adapter (ratelimit_middleware.go)
+
+// RateLimit is a token bucket holding at most Limit requests, it refills completely within Period
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (l RateLimit) ratePerSecond() float64 {
+	return float64(l.Limit) / l.Period.Seconds()
+}
+
+// RateLimitResult is the state of a bucket after taking a token
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	ResetAfter time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, zero when allowed
+}
+
+// RateLimitStore keeps the buckets, implement it (e.g. on Redis) to share limits between instances
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// MemoryRateLimitStore keeps the buckets of a single instance in memory
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(limit, now)
+
+	// refill the bucket for the time elapsed since the last request
+	rate := limit.ratePerSecond()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Limit), updated: now, period: limit.Period}
+		s.buckets[key] = bucket
+	}
+	elapsed := now.Sub(bucket.updated).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(limit.Limit), bucket.tokens+elapsed*rate)
+		bucket.updated = now
+	}
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(bucket.tokens))
+	result.ResetAfter = secondsToDuration((float64(limit.Limit) - bucket.tokens) / rate)
+
+	return result, nil
+}
+
+// sweep drops buckets that are full again, at most once per period of the calling limit
+func (s *MemoryRateLimitStore) sweep(limit RateLimit, now time.Time) {
+	if now.Sub(s.lastSweep) < limit.Period {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// RateLimitConfig configures one rate limited route group, Name keeps the buckets of groups apart
+type RateLimitConfig struct {
+	Name    string
+	Limit   RateLimit
+	Store   RateLimitStore
+	KeyFunc func(c *fiber.Ctx) string
+}
+
+// RateLimitKey identifies the client by its api key, or by IP for every other caller
+func RateLimitKey(c *fiber.Ctx) string {
+	if principal, ok := c.Locals(principalLocalsKey).(*core.Principal); ok && principal.AuthMethod == core.AuthMethodApiKey {
+		return principal.Subject
+	}
+	return "ip:" + c.IP()
+}
+
+// RateLimitIPKey identifies the client by IP only, for the limit in front of the authenticators
+func RateLimitIPKey(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// NewRateLimitMiddleware answers 429 with Retry-After once the bucket of the client is empty. With RateLimitKey it
+// must run after the authenticators so api keys can be used as key, with RateLimitIPKey it runs before them so
+// requests with bogus credentials are limited too. If the store fails the request is let through
+func NewRateLimitMiddleware(config RateLimitConfig) fiber.Handler {
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = RateLimitKey
+	}
+	policy := strconv.Itoa(config.Limit.Limit) + ";w=" + strconv.Itoa(int(config.Limit.Period.Seconds()))
+
+	return func(c *fiber.Ctx) error {
+		key := config.Name + ":" + config.KeyFunc(c)
+
+		// call Take() to take a token of the client bucket and check Error
+		result, err := config.Store.Take(c.UserContext(), key, config.Limit, time.Now())
+		if err != nil {
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Policy", policy)
+		c.Set("RateLimit-Limit", strconv.Itoa(config.Limit.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		}
+
+		return c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
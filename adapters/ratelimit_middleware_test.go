@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	limit := RateLimit{Limit: 2, Period: 10 * time.Second}
+	start := time.Now()
+
+	t.Run("successful until the bucket is empty", func(t *testing.T) {
+		store := NewMemoryRateLimitStore()
+
+		first, _ := store.Take(context.Background(), "client", limit, start)
+		second, _ := store.Take(context.Background(), "client", limit, start)
+		third, _ := store.Take(context.Background(), "client", limit, start)
+
+		assert.True(t, first.Allowed)
+		assert.Equal(t, 1, first.Remaining)
+		assert.True(t, second.Allowed)
+		assert.Equal(t, 0, second.Remaining)
+		assert.False(t, third.Allowed)
+		// one token is refilled every 5 seconds
+		assert.Equal(t, 5*time.Second, third.RetryAfter)
+		assert.Equal(t, 10*time.Second, third.ResetAfter)
+	})
+
+	t.Run("successful after refill", func(t *testing.T) {
+		store := NewMemoryRateLimitStore()
+		store.Take(context.Background(), "client", limit, start)
+		store.Take(context.Background(), "client", limit, start)
+
+		result, _ := store.Take(context.Background(), "client", limit, start.Add(5*time.Second))
+		assert.True(t, result.Allowed)
+
+		result, _ = store.Take(context.Background(), "client", limit, start.Add(5*time.Second))
+		assert.False(t, result.Allowed)
+	})
+
+	t.Run("successful with separate buckets per key", func(t *testing.T) {
+		store := NewMemoryRateLimitStore()
+		store.Take(context.Background(), "client-a", limit, start)
+		store.Take(context.Background(), "client-a", limit, start)
+
+		result, _ := store.Take(context.Background(), "client-b", limit, start)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("successful sweep of idle buckets", func(t *testing.T) {
+		store := NewMemoryRateLimitStore()
+		store.Take(context.Background(), "client-a", limit, start)
+		store.Take(context.Background(), "client-b", limit, start.Add(time.Minute))
+
+		assert.Len(t, store.buckets, 1)
+	})
+}
+
+// failingRateLimitStore simulates a store that is not reachable
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store error")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	setupApp := func(store RateLimitStore) *fiber.App {
+		app := fiber.New()
+		// authenticate requests with the X-API-Key header as the given subject
+		app.Use(func(c *fiber.Ctx) error {
+			if subject := c.Get(ApiKeyHeader); subject != "" {
+				setPrincipal(c, &core.Principal{Subject: subject, AuthMethod: core.AuthMethodApiKey})
+			}
+			return c.Next()
+		})
+		app.Use(NewRateLimitMiddleware(RateLimitConfig{
+			Name:  "customers",
+			Limit: RateLimit{Limit: 2, Period: time.Minute},
+			Store: store,
+		}))
+		app.Post("/customers", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusCreated)
+		})
+		return app
+	}
+	send := func(app *fiber.App, apiKey string) *http.Response {
+		req := httptest.NewRequest("POST", "/customers", nil)
+		if apiKey != "" {
+			req.Header.Set(ApiKeyHeader, apiKey)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Success case
+	t.Run("successful with RateLimit headers", func(t *testing.T) {
+		app := setupApp(NewMemoryRateLimitStore())
+
+		resp := send(app, "")
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", resp.Header.Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+	})
+
+	t.Run("successful with separate limits per api key", func(t *testing.T) {
+		app := setupApp(NewMemoryRateLimitStore())
+		send(app, "apikey:1")
+		send(app, "apikey:1")
+
+		assert.Equal(t, fiber.StatusTooManyRequests, send(app, "apikey:1").StatusCode)
+		assert.Equal(t, fiber.StatusCreated, send(app, "apikey:2").StatusCode)
+		assert.Equal(t, fiber.StatusCreated, send(app, "").StatusCode)
+	})
+
+	t.Run("successful when the store fails", func(t *testing.T) {
+		app := setupApp(failingRateLimitStore{})
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, fiber.StatusCreated, send(app, "").StatusCode)
+		}
+	})
+
+	// Failure case
+	t.Run("(fail) bogus credentials limited before authentication", func(t *testing.T) {
+		app := fiber.New()
+		app.Use(NewRateLimitMiddleware(RateLimitConfig{
+			Name: "ip", Limit: RateLimit{Limit: 2, Period: time.Minute}, KeyFunc: RateLimitIPKey,
+		}))
+		// an authenticator rejecting every key
+		app.Post("/customers", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		})
+
+		assert.Equal(t, fiber.StatusUnauthorized, send(app, "itmx_guess_1").StatusCode)
+		assert.Equal(t, fiber.StatusUnauthorized, send(app, "itmx_guess_2").StatusCode)
+		// changing the key does not get a new bucket
+		assert.Equal(t, fiber.StatusTooManyRequests, send(app, "itmx_guess_3").StatusCode)
+	})
+
+	t.Run("(fail) too many requests", func(t *testing.T) {
+		app := setupApp(NewMemoryRateLimitStore())
+		send(app, "")
+		send(app, "")
+
+		resp := send(app, "")
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+		assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	})
+}
@@ -0,0 +1,70 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the spans
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// tracerName is the instrumentation scope of the spans of the adapters
+const tracerName = "github.com/fiatfour/itmx-crud-hex/adapters"
+
+var ErrUnknownTracingExporter = errors.New("unknown tracing exporter")
+
+// NewTracerProvider returns the provider of the spans of the server: stdout writes them to out as JSON lines,
+// otlp sends them to the collector at endpoint and none samples nothing, while the traceparent of callers is still
+// passed on. Shutdown flushes the spans not exported yet
+func NewTracerProvider(ctx context.Context, exporter string, endpoint string, serviceName string, out io.Writer) (*sdktrace.TracerProvider, error) {
+	res := resource.NewSchemaless(semconv.ServiceName(serviceName))
+
+	switch exporter {
+	case TracingExporterNone:
+		return sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithSampler(sdktrace.NeverSample())), nil
+
+	case TracingExporterStdout:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithBatcher(spanExporter)), nil
+
+	case TracingExporterOTLP:
+		var options []otlptracehttp.Option
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		spanExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithBatcher(spanExporter)), nil
+
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownTracingExporter, exporter)
+	}
+}
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,92 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// * Secondary adapter (tracing_customer_repository.go)
+
+// TracingCustomerRepository decorates a CustomerRepository with a span around every method, the SQL statements
+// of the gorm adapter are its children (see GormTracing)
+type TracingCustomerRepository struct {
+	r      core.CustomerRepository
+	tracer trace.Tracer
+}
+
+// NewCustomerRepositoryTracing returns a decorator that traces a CustomerRepository, for the repositories of the
+// unit of work as well
+func NewCustomerRepositoryTracing(tp trace.TracerProvider) CustomerRepositoryDecorator {
+	tracer := tp.Tracer(tracerName)
+	return func(r core.CustomerRepository) core.CustomerRepository {
+		return &TracingCustomerRepository{r: r, tracer: tracer}
+	}
+}
+
+func (r *TracingCustomerRepository) Save(ctx context.Context, customer *core.Customer) (err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Save")
+	defer func() { endSpan(span, err) }()
+	return r.r.Save(ctx, customer)
+}
+
+func (r *TracingCustomerRepository) Get(ctx context.Context, customerId uint) (_ *core.Customer, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Get")
+	defer func() { endSpan(span, err) }()
+	return r.r.Get(ctx, customerId)
+}
+
+func (r *TracingCustomerRepository) GetAll(ctx context.Context) (_ []core.Customer, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.GetAll")
+	defer func() { endSpan(span, err) }()
+	return r.r.GetAll(ctx)
+}
+
+func (r *TracingCustomerRepository) Update(ctx context.Context, customerId uint, customer *core.Customer) (_ *core.Customer, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Update")
+	defer func() { endSpan(span, err) }()
+	return r.r.Update(ctx, customerId, customer)
+}
+
+func (r *TracingCustomerRepository) Delete(ctx context.Context, customerId uint) (err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Delete")
+	defer func() { endSpan(span, err) }()
+	return r.r.Delete(ctx, customerId)
+}
+
+func (r *TracingCustomerRepository) Search(ctx context.Context, customerId uint) (err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Search")
+	defer func() { endSpan(span, err) }()
+	return r.r.Search(ctx, customerId)
+}
+
+func (r *TracingCustomerRepository) ForEachBatch(ctx context.Context, batchSize int, fn func(customers []core.Customer) error) (err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.ForEachBatch")
+	defer func() { endSpan(span, err) }()
+	return r.r.ForEachBatch(ctx, batchSize, fn)
+}
+
+func (r *TracingCustomerRepository) ApplyBatch(ctx context.Context, operations []core.BatchOperation) (_ []uint, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.ApplyBatch")
+	defer func() { endSpan(span, err) }()
+	return r.r.ApplyBatch(ctx, operations)
+}
+
+func (r *TracingCustomerRepository) Changes(ctx context.Context, since uint, limit int) (_ []core.ChangeRecord, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.Changes")
+	defer func() { endSpan(span, err) }()
+	return r.r.Changes(ctx, since, limit)
+}
+
+func (r *TracingCustomerRepository) ChangeHead(ctx context.Context) (_ uint, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.ChangeHead")
+	defer func() { endSpan(span, err) }()
+	return r.r.ChangeHead(ctx)
+}
+
+func (r *TracingCustomerRepository) ExistingNames(ctx context.Context, names []string) (_ []string, err error) {
+	ctx, span := r.tracer.Start(ctx, "CustomerRepository.ExistingNames")
+	defer func() { endSpan(span, err) }()
+	return r.r.ExistingNames(ctx, names)
+}
@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingCustomerService decorates a CustomerService with a span around every method
+type TracingCustomerService struct {
+	s      core.CustomerService
+	tracer trace.Tracer
+}
+
+func NewTracingCustomerService(s core.CustomerService, tp trace.TracerProvider) core.CustomerService {
+	return &TracingCustomerService{s: s, tracer: tp.Tracer(tracerName)}
+}
+
+func (s *TracingCustomerService) CreateCustomer(ctx context.Context, customer core.Customer) (err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.CreateCustomer")
+	defer func() { endSpan(span, err) }()
+	return s.s.CreateCustomer(ctx, customer)
+}
+
+func (s *TracingCustomerService) GetCustomerById(ctx context.Context, customerId uint) (_ *core.Customer, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.GetCustomerById")
+	defer func() { endSpan(span, err) }()
+	return s.s.GetCustomerById(ctx, customerId)
+}
+
+func (s *TracingCustomerService) GetAllCustomer(ctx context.Context) (_ []core.Customer, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.GetAllCustomer")
+	defer func() { endSpan(span, err) }()
+	return s.s.GetAllCustomer(ctx)
+}
+
+// ExportCustomers is traced until the stream is returned, reading it is up to the caller
+func (s *TracingCustomerService) ExportCustomers(ctx context.Context) (_ core.CustomerStream, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.ExportCustomers")
+	defer func() { endSpan(span, err) }()
+	return s.s.ExportCustomers(ctx)
+}
+
+func (s *TracingCustomerService) UpdateCustomer(ctx context.Context, customerId uint, customer *core.Customer) (_ *core.Customer, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.UpdateCustomer")
+	defer func() { endSpan(span, err) }()
+	return s.s.UpdateCustomer(ctx, customerId, customer)
+}
+
+func (s *TracingCustomerService) DeleteCustomer(ctx context.Context, customerId uint) (err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.DeleteCustomer")
+	defer func() { endSpan(span, err) }()
+	return s.s.DeleteCustomer(ctx, customerId)
+}
+
+func (s *TracingCustomerService) SearchCustomerById(ctx context.Context, customerId uint) (err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.SearchCustomerById")
+	defer func() { endSpan(span, err) }()
+	return s.s.SearchCustomerById(ctx, customerId)
+}
+
+// ValidateName has no context to trace
+func (s *TracingCustomerService) ValidateName(customerName string) error {
+	return s.s.ValidateName(customerName)
+}
+
+func (s *TracingCustomerService) ExecuteBatch(ctx context.Context, operations []core.BatchOperation, atomic bool) (_ []core.BatchResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.ExecuteBatch")
+	defer func() { endSpan(span, err) }()
+	return s.s.ExecuteBatch(ctx, operations, atomic)
+}
+
+func (s *TracingCustomerService) ImportCustomers(ctx context.Context, source core.CustomerSource, dryRun bool) (_ *core.ImportReport, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.ImportCustomers")
+	defer func() { endSpan(span, err) }()
+	return s.s.ImportCustomers(ctx, source, dryRun)
+}
+
+// StreamCustomerEvents is traced until the stream is returned, the stream lives as long as its client
+func (s *TracingCustomerService) StreamCustomerEvents(ctx context.Context, lastEventId *uint) (_ core.CustomerEventStream, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.StreamCustomerEvents")
+	defer func() { endSpan(span, err) }()
+	return s.s.StreamCustomerEvents(ctx, lastEventId)
+}
+
+func (s *TracingCustomerService) GetChanges(ctx context.Context, since uint, limit int) (_ []core.ChangeRecord, err error) {
+	ctx, span := s.tracer.Start(ctx, "CustomerService.GetChanges")
+	defer func() { endSpan(span, err) }()
+	return s.s.GetChanges(ctx, since, limit)
+}
@@ -0,0 +1,63 @@
+package adapters
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ! Primary adapter (tracing_middleware.go)
+
+// NewTracingMiddleware starts the server span of every request as child of the W3C traceparent header of the
+// caller, if any, and passes it on through the user context. The traceparent of the span is returned, so a
+// caller without tracing can still look its request up. It must run before the middleware that set the user context
+func NewTracingMiddleware(tp trace.TracerProvider) fiber.Handler {
+	tracer := tp.Tracer(tracerName)
+	propagator := propagation.TraceContext{}
+
+	return func(c *fiber.Ctx) error {
+		ctx := propagator.Extract(c.UserContext(), fiberHeaderCarrier{c})
+		ctx, span := tracer.Start(ctx, c.Method(), trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Method()), semconv.URLPath(c.Path())))
+		defer span.End()
+		c.SetUserContext(ctx)
+		propagator.Inject(ctx, fiberHeaderCarrier{c})
+
+		err := c.Next()
+
+		status := responseStatus(c, err)
+
+		// the route is known once the request is routed, it names the span like "GET /customers/:id"
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(semconv.HTTPRoute(c.Route().Path), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
+
+// fiberHeaderCarrier reads the traceparent from the request headers and writes it to the response headers
+type fiberHeaderCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h fiberHeaderCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h fiberHeaderCarrier) Set(key string, value string) {
+	h.c.Set(key, value)
+}
+
+func (h fiberHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(h.c.GetReqHeaders()))
+	for key := range h.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
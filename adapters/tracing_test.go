@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanNamed finds the span called name among the exported spans
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not exported", "no span %q in %v", name, spans)
+	return tracetest.SpanStub{}
+}
+
+func TestTracing(t *testing.T) {
+	db := setupTestDB()
+	t.Cleanup(func() {
+		// Close the database so other tests start empty
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	require.NoError(t, db.Use(NewGormTracing(tp)))
+
+	tracing := NewCustomerRepositoryTracing(tp)
+	service := NewTracingCustomerService(core.NewCustomerService(tracing(NewGormCustomerRepository(db)), NewGormUnitOfWork(db, tracing)), tp)
+	customerHandler := NewHttpCustomerHandler(service)
+	app := fiber.New()
+	app.Use(NewTracingMiddleware(tp))
+	app.Get("/customers/:id", customerHandler.GetCustomerHandler)
+	require.NoError(t, service.CreateCustomer(context.Background(), core.Customer{Name: "Alice", Age: 30}))
+
+	// Success case
+	t.Run("successful trace from traceparent to SQL", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest("GET", "/customers/1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+		spans := exporter.GetSpans()
+		request := spanNamed(t, spans, "GET /customers/:id")
+		serviceSpan := spanNamed(t, spans, "CustomerService.GetCustomerById")
+		repoSpan := spanNamed(t, spans, "CustomerRepository.Get")
+		query := spanNamed(t, spans, "gorm.query")
+
+		// every span is part of the trace of the caller, each the child of the layer above
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", request.Parent.SpanID().String())
+		assert.Equal(t, request.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+		assert.Equal(t, serviceSpan.SpanContext.SpanID(), repoSpan.Parent.SpanID())
+		assert.Equal(t, repoSpan.SpanContext.SpanID(), query.Parent.SpanID())
+
+		var statement string
+		for _, attribute := range query.Attributes {
+			if attribute.Key == "db.query.text" {
+				statement = attribute.Value.AsString()
+			}
+		}
+		assert.Contains(t, statement, "SELECT * FROM")
+		assert.Contains(t, statement, "customers")
+	})
+
+	t.Run("successful trace of a write through the unit of work", func(t *testing.T) {
+		exporter.Reset()
+
+		// CreateCustomer() saves in a transaction and check the span of the repository
+		assert.NoError(t, service.CreateCustomer(context.Background(), core.Customer{Name: "Bob", Age: 40}))
+
+		spans := exporter.GetSpans()
+		serviceSpan := spanNamed(t, spans, "CustomerService.CreateCustomer")
+		repoSpan := spanNamed(t, spans, "CustomerRepository.Save")
+		assert.Equal(t, serviceSpan.SpanContext.SpanID(), repoSpan.Parent.SpanID())
+	})
+
+	// Failure case
+	t.Run("(fail) error recorded on the span", func(t *testing.T) {
+		exporter.Reset()
+		resp, err := app.Test(httptest.NewRequest("GET", "/customers/0", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		spans := exporter.GetSpans()
+		serviceSpan := spanNamed(t, spans, "CustomerService.GetCustomerById")
+		assert.Equal(t, codes.Error, serviceSpan.Status.Code)
+		assert.Equal(t, core.ErrInvalidCustomerId.Error(), serviceSpan.Status.Description)
+		// client errors are no errors of the server span
+		assert.Equal(t, codes.Unset, spanNamed(t, spans, "GET /customers/:id").Status.Code)
+	})
+
+	t.Run("(fail) unknown exporter", func(t *testing.T) {
+		_, err := NewTracerProvider(context.Background(), "jaeger", "", "test", &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrUnknownTracingExporter)
+	})
+}
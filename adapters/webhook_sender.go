@@ -0,0 +1,117 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// * Secondary adapter (webhook_sender.go)
+
+// Headers of a webhook request, the signature is "t=<unix seconds>,v1=<hex hmac-sha256>"
+const (
+	WebhookIdHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+// SignWebhook is the HMAC-SHA256 with secret of "<timestamp>.<body>" in hex, receivers compute it again to check
+// that the body comes from us, the timestamp lets them reject old requests that are replayed
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HTTPWebhookSender posts the deliveries as JSON
+type HTTPWebhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewHTTPWebhookSender sends with client, a nil client only connects to the addresses allowed by
+// core.CheckWebhookAddress
+func NewHTTPWebhookSender(client *http.Client) *HTTPWebhookSender {
+	if client == nil {
+		client = newWebhookClient()
+	}
+	return &HTTPWebhookSender{client: client, now: time.Now}
+}
+
+// newWebhookClient checks the address of every connection once the host name is resolved, so a host name that
+// resolves to an internal address after the subscription was created (DNS rebinding) or a redirect to one is
+// refused too. There is no proxy, it would be the only address checked
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: controlWebhookAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// controlWebhookAddress runs before the dialer connects to address
+func controlWebhookAddress(network, address string, conn syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return core.CheckWebhookAddress(addrPort.Addr())
+}
+
+// webhookBody is sent to the receiver, ID is the same for every attempt and subscription of an event
+type webhookBody struct {
+	ID         uint            `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Event      json.RawMessage `json:"event"`
+}
+
+func (s *HTTPWebhookSender) Send(ctx context.Context, subscription *core.WebhookSubscription, delivery *core.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(webhookBody{
+		ID:         delivery.MessageID,
+		Type:       delivery.EventType,
+		OccurredAt: delivery.OccurredAt,
+		Event:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := s.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIdHeader, strconv.FormatUint(uint64(delivery.MessageID), 10))
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookSignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhook(subscription.Secret, timestamp, body)))
+
+	// send the request and check Error, the body of the response is not used
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// * Secondary adapter (writer_publisher.go)
+
+// WriterPublisher writes every message as one JSON line, it stands in for a message broker during development
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+type publishedMessage struct {
+	ID         uint            `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Event      json.RawMessage `json:"event"`
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, message core.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return json.NewEncoder(p.w).Encode(publishedMessage{
+		ID:         message.ID,
+		Type:       message.EventType,
+		OccurredAt: message.OccurredAt,
+		Event:      message.Payload,
+	})
+}
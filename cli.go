@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/config"
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/fiatfour/itmx-crud-hex/migrations"
+	"gorm.io/gorm"
+)
+
+// cli is what the commands share, the database is opened by the first command that needs it
+type cli struct {
+	cfg    *config.Config
+	out    io.Writer
+	logger *slog.Logger
+	db     *gorm.DB
+}
+
+// newLogger returns the structured logger of the configuration, writing to w
+func newLogger(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	var level slog.Level
+	// the level is validated by the config, an unknown one stays at info
+	level.UnmarshalText([]byte(cfg.Level))
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+// database opens the configured database once
+func (c *cli) database() (*gorm.DB, error) {
+	if c.db != nil {
+		return c.db, nil
+	}
+
+	db, err := adapters.OpenDatabase(c.cfg.Database.Driver, c.cfg.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+	// log the SQL statements with the logger of their context, slow ones as warning
+	db.Logger = adapters.NewGormLogger(c.cfg.Log.SlowQuery)
+	c.db = db
+	return db, nil
+}
+
+// customerService returns the CustomerService of the configured database, it refuses a schema that is behind.
+// The commands run with database access, so the service is used without the authorization decorator
+func (c *cli) customerService(ctx context.Context) (core.CustomerService, error) {
+	db, err := c.database()
+	if err != nil {
+		return nil, err
+	}
+	if err := migrations.NewMigrator(db, migrations.All).CheckCurrent(ctx); err != nil {
+		return nil, fmt.Errorf("%w, run migrate up first", err)
+	}
+
+	return core.NewCustomerService(adapters.NewGormCustomerRepository(db), adapters.NewGormUnitOfWork(db)), nil
+}
+
+// close closes the database when a command opened it, closing again does nothing
+func (c *cli) close() error {
+	if c.db == nil {
+		return nil
+	}
+	sqlDB, err := c.db.DB()
+	c.db = nil
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// newFlagSet returns the flags of a command, they are parsed without exiting the process
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.out)
+	return flags
+}
+
+// parseFlags parses the flags before and after the positional arguments, as in "customers delete <id> -tenant
+// <tenant>", the flag package alone stops at the first positional one. It returns the positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// tenantFlag adds the -tenant flag of the commands that read or write customers
+func tenantFlag(flags *flag.FlagSet) *string {
+	return flags.String("tenant", core.DefaultTenantID, "tenant of the customers")
+}
@@ -0,0 +1,331 @@
+// Package config loads the configuration of the service. Every setting has a default, which a YAML (or JSON) file,
+// then an environment variable and then a command line flag override in that order. The name of the environment
+// variable and of the flag follow the path of the setting in the file:
+//
+//	database:
+//	  dsn: customers.db     # DATABASE_DSN=customers.db or -database.dsn customers.db
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server and of the commands
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Features    FeaturesConfig    `yaml:"features"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Log         LogConfig         `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr"`
+	// MetricsAddr serves /metrics apart from the API, it is meant to be reachable only from inside the network
+	MetricsAddr string `yaml:"metrics_addr"`
+	// ShutdownTimeout bounds draining the requests on SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// WorkerStopTimeout bounds stopping the background workers once the requests are drained
+	WorkerStopTimeout time.Duration `yaml:"worker_stop_timeout"`
+}
+
+type DatabaseConfig struct {
+	// Driver is sqlite or postgres
+	Driver string `yaml:"driver"`
+	// DSN is the path of the sqlite database or the connection string of postgres, its password is redacted
+	DSN string `yaml:"dsn"`
+}
+
+type AuthConfig struct {
+	JWKSFile   string `yaml:"jwks_file"`
+	Issuer     string `yaml:"issuer"`
+	Audience   string `yaml:"audience"`
+	PolicyFile string `yaml:"policy_file"`
+}
+
+// RateLimitConfig are the requests per Period of one client in each route group. PerIP limits every IP before
+// its credentials are checked, the other limits apply per api key (or IP) after authentication
+type RateLimitConfig struct {
+	Period         time.Duration `yaml:"period"`
+	PerIP          int           `yaml:"per_ip"`
+	Customers      int           `yaml:"customers"`
+	CustomerWrites int           `yaml:"customer_writes"`
+	Admin          int           `yaml:"admin"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long the response of a key is replayed
+	TTL time.Duration `yaml:"ttl"`
+	// LockTimeout is how long a key stays reserved by a request that never completes, e.g. after a crash
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+	// CleanupInterval is how often the expired records are deleted
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+// FeaturesConfig switches optional parts of the service on and off
+type FeaturesConfig struct {
+	// Webhooks queues and sends the webhook deliveries and serves /admin/webhooks
+	Webhooks bool `yaml:"webhooks"`
+	// StdoutEvents writes the customer events relayed from the outbox to stdout as NDJSON, it is meant for local
+	// development only, the events carry personal data
+	StdoutEvents bool `yaml:"stdout_events"`
+}
+
+// TracingConfig chooses where the spans of the server go
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP url of the collector, the OTEL_EXPORTER_OTLP_* variables apply when it is empty
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"service_name"`
+}
+
+// LogConfig configures the structured logs written to stderr
+type LogConfig struct {
+	// Level is debug, info, warn or error, the SQL statements are logged at debug
+	Level string `yaml:"level"`
+	// Format is json or text
+	Format string `yaml:"format"`
+	// SlowQuery is the duration above which a SQL statement is logged as warning
+	SlowQuery time.Duration `yaml:"slow_query"`
+}
+
+// Default is the configuration of a local development server
+func Default() Config {
+	return Config{
+		Server:   ServerConfig{Addr: "localhost:8080", MetricsAddr: "localhost:9090", ShutdownTimeout: 15 * time.Second, WorkerStopTimeout: 10 * time.Second},
+		Database: DatabaseConfig{Driver: "sqlite", DSN: "customers.db"},
+		Auth:     AuthConfig{JWKSFile: "jwks.json", PolicyFile: "policy.yaml"},
+		RateLimit: RateLimitConfig{
+			Period:         time.Minute,
+			PerIP:          600,
+			Customers:      300,
+			CustomerWrites: 60,
+			Admin:          30,
+		},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour, LockTimeout: time.Minute, CleanupInterval: 10 * time.Minute},
+		Features:    FeaturesConfig{Webhooks: true},
+		Tracing:     TracingConfig{Exporter: "none", ServiceName: "itmx-crud-hex"},
+		Log:         LogConfig{Level: "info", Format: "json", SlowQuery: 200 * time.Millisecond},
+	}
+}
+
+// EnvConfigFile names the config file when no -config flag is given
+const EnvConfigFile = "CONFIG_FILE"
+
+// Load builds the configuration from the defaults, the config file of -config or CONFIG_FILE, the environment
+// variables of lookupEnv and the flags of args, and validates it. The arguments after the flags are returned
+func Load(args []string, lookupEnv func(key string) (string, bool)) (*Config, []string, error) {
+	config := Default()
+	settings := settingsOf(&config)
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of the YAML or JSON config file, or "+EnvConfigFile)
+	for _, setting := range settings {
+		flags.String(setting.path, "", fmt.Sprintf("%s (env %s, default %q)", setting.path, setting.env, setting.String()))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// the config file overrides the defaults
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(EnvConfigFile)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, nil, fmt.Errorf("invalid config file: %w", err)
+		}
+	}
+
+	// environment variables override the file, flags override everything
+	for _, setting := range settings {
+		if value, ok := lookupEnv(setting.env); ok {
+			if err := setting.Set(value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", setting.env, err)
+			}
+		}
+	}
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		for _, setting := range settings {
+			if setting.path == f.Name && err == nil {
+				if setErr := setting.Set(f.Value.String()); setErr != nil {
+					err = fmt.Errorf("-%s: %w", f.Name, setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &config, flags.Args(), nil
+}
+
+// Validate returns every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	if c.Server.MetricsAddr == "" || c.Server.MetricsAddr == c.Server.Addr {
+		errs = append(errs, errors.New("server.metrics_addr is required and must differ from server.addr"))
+	}
+	if c.Server.ShutdownTimeout <= 0 || c.Server.WorkerStopTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout and worker_stop_timeout must be positive"))
+	}
+	if c.Database.Driver != "sqlite" && c.Database.Driver != "postgres" {
+		errs = append(errs, fmt.Errorf("database.driver must be sqlite or postgres, not %q", c.Database.Driver))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn is required"))
+	}
+	if c.Auth.JWKSFile == "" {
+		errs = append(errs, errors.New("auth.jwks_file is required"))
+	}
+	if c.Auth.PolicyFile == "" {
+		errs = append(errs, errors.New("auth.policy_file is required"))
+	}
+	if c.RateLimit.Period <= 0 {
+		errs = append(errs, errors.New("rate_limit.period must be positive"))
+	}
+	if c.RateLimit.PerIP <= 0 || c.RateLimit.Customers <= 0 || c.RateLimit.CustomerWrites <= 0 || c.RateLimit.Admin <= 0 {
+		errs = append(errs, errors.New("rate_limit.per_ip, customers, customer_writes and admin must be positive"))
+	}
+	if c.Idempotency.TTL <= 0 || c.Idempotency.LockTimeout <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl and lock_timeout must be positive"))
+	}
+	if c.Idempotency.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("idempotency.cleanup_interval must be positive"))
+	}
+	if c.Tracing.Exporter != "none" && c.Tracing.Exporter != "stdout" && c.Tracing.Exporter != "otlp" {
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, stdout or otlp, not %q", c.Tracing.Exporter))
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, not %q", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("log.format must be json or text, not %q", c.Log.Format))
+	}
+	if c.Log.SlowQuery <= 0 {
+		errs = append(errs, errors.New("log.slow_query must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that can be shown, the passwords of the dsn are hidden
+func (c Config) Redacted() Config {
+	c.Database.DSN = redactDSN(c.Database.DSN)
+	return c
+}
+
+// Print writes the configuration as YAML, with its secrets redacted
+func (c Config) Print() (string, error) {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// dsnPassword finds the password of a "key=value" connection string
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S*)`)
+
+func redactDSN(dsn string) string {
+	if parsed, err := url.Parse(dsn); err == nil && parsed.User != nil {
+		return parsed.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// setting is one leaf of Config
+type setting struct {
+	path  string
+	env   string
+	value reflect.Value
+}
+
+// settingsOf lists the leaves of config, the flag of a setting is its yaml path and its environment variable the
+// path in upper case with underscores
+func settingsOf(config *Config) []setting {
+	var settings []setting
+	var walk func(value reflect.Value, path string)
+	walk = func(value reflect.Value, path string) {
+		for index := 0; index < value.NumField(); index++ {
+			name := strings.Split(value.Type().Field(index).Tag.Get("yaml"), ",")[0]
+			field := value.Field(index)
+			if path != "" {
+				name = path + "." + name
+			}
+			if field.Kind() == reflect.Struct {
+				walk(field, name)
+				continue
+			}
+			env := strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+			settings = append(settings, setting{path: name, env: env, value: field})
+		}
+	}
+	walk(reflect.ValueOf(config).Elem(), "")
+	return settings
+}
+
+func (s setting) Set(value string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(value)
+	case time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(duration))
+	case int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(number))
+	case bool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(enabled)
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	return fmt.Sprint(s.value.Interface())
+}
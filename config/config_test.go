@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envOf looks up the variables of env like os.LookupEnv
+func envOf(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	// Success case
+	t.Run("successful defaults", func(t *testing.T) {
+		config, args, err := Load([]string{"migrate", "up"}, envOf(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, Default(), *config)
+		assert.Equal(t, []string{"migrate", "up"}, args)
+		// the events carry personal data, they are not written to stdout unless switched on
+		assert.False(t, config.Features.StdoutEvents)
+	})
+
+	t.Run("successful file, env and flags in precedence", func(t *testing.T) {
+		path := writeTestFile(t, "config.yaml", "server:\n  addr: :9000\ndatabase:\n  driver: postgres\n  dsn: host=db\nidempotency:\n  ttl: 1h\nrate_limit:\n  admin: 5\n")
+		env := envOf(map[string]string{
+			EnvConfigFile:             path,
+			"DATABASE_DSN":            "host=env",
+			"RATE_LIMIT_ADMIN":        "10",
+			"FEATURES_WEBHOOKS":       "false",
+			"IDEMPOTENCY_TTL":         "2h",
+			"SERVER_SHUTDOWN_TIMEOUT": "30s",
+			"AUTH_POLICY_FILE":        "policy.json",
+			"SOMETHING_UNRELATED":     "x",
+		})
+
+		config, args, err := Load([]string{"-database.dsn", "host=flag", "-idempotency.ttl=3h", "serve"}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"serve"}, args)
+		assert.Equal(t, ":9000", config.Server.Addr)
+		assert.Equal(t, "postgres", config.Database.Driver)
+		assert.Equal(t, "host=flag", config.Database.DSN)
+		assert.Equal(t, 10, config.RateLimit.Admin)
+		assert.Equal(t, 3*time.Hour, config.Idempotency.TTL)
+		assert.Equal(t, 30*time.Second, config.Server.ShutdownTimeout)
+		assert.False(t, config.Features.Webhooks)
+		assert.Equal(t, "policy.json", config.Auth.PolicyFile)
+		// untouched settings keep their default
+		assert.Equal(t, 300, config.RateLimit.Customers)
+	})
+
+	t.Run("successful -config flag over CONFIG_FILE", func(t *testing.T) {
+		path := writeTestFile(t, "config.json", `{"server": {"addr": ":7000"}}`)
+
+		config, _, err := Load([]string{"-config", path}, envOf(map[string]string{EnvConfigFile: "missing.yaml"}))
+		assert.NoError(t, err)
+		assert.Equal(t, ":7000", config.Server.Addr)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid settings are reported together", func(t *testing.T) {
+		_, _, err := Load([]string{"-database.driver", "mysql", "-server.addr="}, envOf(map[string]string{"RATE_LIMIT_PERIOD": "0s"}))
+		assert.Equal(t, "invalid config: server.addr is required\n"+
+			"database.driver must be sqlite or postgres, not \"mysql\"\n"+
+			"rate_limit.period must be positive", err.Error())
+	})
+
+	t.Run("(fail) metrics on the address of the API", func(t *testing.T) {
+		_, _, err := Load([]string{"-server.metrics_addr", "localhost:8080"}, envOf(nil))
+		assert.Equal(t, "invalid config: server.metrics_addr is required and must differ from server.addr", err.Error())
+	})
+
+	t.Run("(fail) invalid values", func(t *testing.T) {
+		_, _, err := Load(nil, envOf(map[string]string{"RATE_LIMIT_ADMIN": "many"}))
+		assert.ErrorContains(t, err, "RATE_LIMIT_ADMIN: ")
+
+		_, _, err = Load([]string{"-idempotency.ttl", "1 day"}, envOf(nil))
+		assert.ErrorContains(t, err, "-idempotency.ttl: ")
+	})
+
+	t.Run("(fail) missing config file", func(t *testing.T) {
+		_, _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, envOf(nil))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestPrint(t *testing.T) {
+	// Success case
+	t.Run("successful with the password of an url redacted", func(t *testing.T) {
+		config := Default()
+		config.Database.DSN = "postgres://app:s3cret@db:5432/customers"
+
+		out, err := config.Print()
+		assert.NoError(t, err)
+		assert.Contains(t, out, "dsn: postgres://app:xxxxx@db:5432/customers\n")
+		assert.Contains(t, out, "ttl: 24h0m0s\n")
+		assert.NotContains(t, out, "s3cret")
+		// the config itself is not changed
+		assert.Equal(t, "postgres://app:s3cret@db:5432/customers", config.Database.DSN)
+	})
+
+	t.Run("successful with the password of a connection string redacted", func(t *testing.T) {
+		config := Default()
+		config.Database.DSN = "host=db user=app password='s3 cret' dbname=customers"
+
+		assert.Equal(t, "host=db user=app password=xxxxx dbname=customers", config.Redacted().Database.DSN)
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var errConfigUsage = errors.New("usage: config print")
+
+// runConfig runs "config print", it writes the effective configuration with its secrets redacted
+func runConfig(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errConfigUsage
+	}
+
+	text, err := cli.cfg.Print()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cli.out, text)
+	return nil
+}
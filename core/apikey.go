@@ -0,0 +1,17 @@
+package core
+
+import "time"
+
+// ApiKey is a credential for machine clients, only the hash of the secret is stored
+type ApiKey struct {
+	ID         uint   `gorm:"primaryKey"`
+	TenantID   string `gorm:"not null;default:''"`
+	Name       string
+	Prefix     string   `gorm:"uniqueIndex"`
+	SecretHash string   `json:"-"`
+	Scopes     []string `gorm:"serializer:json"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
@@ -0,0 +1,19 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+//* Secondary Port (apikey_repository.go)
+
+// Get and GetAll are scoped to the tenant of ctx (see TenantFromContext), GetByPrefix is not: it authenticates
+// callers before their tenant is known
+type ApiKeyRepository interface {
+	Save(ctx context.Context, apiKey *ApiKey) error                           // Port
+	Get(ctx context.Context, apiKeyId uint) (*ApiKey, error)                  // Port
+	GetByPrefix(ctx context.Context, prefix string) (*ApiKey, error)          // Port
+	GetAll(ctx context.Context) ([]ApiKey, error)                             // Port
+	Update(ctx context.Context, apiKey *ApiKey) error                         // Port
+	TouchLastUsed(ctx context.Context, apiKeyId uint, usedAt time.Time) error // Port
+}
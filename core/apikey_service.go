@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ! Primary Port (apikey_service.go)
+type ApiKeyService interface {
+	IssueApiKey(ctx context.Context, name string, scopes []string, ttl time.Duration) (*ApiKey, string, error)
+	GetAllApiKey(ctx context.Context) ([]ApiKey, error)
+	RevokeApiKey(ctx context.Context, apiKeyId uint) error
+	RotateApiKey(ctx context.Context, apiKeyId uint, ttl time.Duration) (*ApiKey, string, error)
+	AuthenticateApiKey(ctx context.Context, rawKey string) (*Principal, error)
+}
+
+var (
+	ErrInvalidApiKey   = errors.New("invalid api key")
+	ErrApiKeyRevoked   = errors.New("api key is revoked")
+	ErrApiKeyNameEmpty = errors.New("api key name must not be empty")
+)
+
+const (
+	// a plain api key looks like "itmx_<prefix>_<secret>", the prefix is stored to look the key up
+	apiKeyMarker      = "itmx"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+	apiKeyTouchPeriod = time.Minute
+)
+
+// Implement ApiKeyRepository
+type apiKeyServiceImpl struct {
+	r   ApiKeyRepository
+	now func() time.Time
+}
+
+func NewApiKeyService(repo ApiKeyRepository) ApiKeyService {
+	return &apiKeyServiceImpl{r: repo, now: time.Now}
+}
+
+// IssueApiKey issues a key of the tenant of ctx, an admin can only issue keys for its own tenant
+func (s *apiKeyServiceImpl) IssueApiKey(ctx context.Context, name string, scopes []string, ttl time.Duration) (*ApiKey, string, error) {
+	// Business logic...
+	// Check name
+	if strings.TrimSpace(name) == "" {
+		return &ApiKey{}, "", ErrApiKeyNameEmpty
+	}
+
+	apiKey := &ApiKey{TenantID: TenantFromContext(ctx), Name: name, Scopes: scopes, CreatedAt: s.now()}
+	rawKey, err := s.assignSecret(apiKey, ttl)
+	if err != nil {
+		return &ApiKey{}, "", err
+	}
+
+	// call Save() to insert the ApiKey in gorm adapter
+	if err := s.r.Save(ctx, apiKey); err != nil {
+		return &ApiKey{}, "", err
+	}
+
+	return apiKey, rawKey, nil
+}
+
+func (s *apiKeyServiceImpl) GetAllApiKey(ctx context.Context) ([]ApiKey, error) {
+	// call GetAll() for get all ApiKeys from gorm adapter
+	apiKeys, err := s.r.GetAll(ctx)
+	if err != nil {
+		return []ApiKey{}, err
+	}
+
+	return apiKeys, nil
+}
+
+func (s *apiKeyServiceImpl) RevokeApiKey(ctx context.Context, apiKeyId uint) error {
+	// call Get() to find the ApiKey and check Error
+	apiKey, err := s.r.Get(ctx, apiKeyId)
+	if err != nil {
+		return err
+	}
+
+	// revoking twice keeps the first revocation time
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+	revokedAt := s.now()
+	apiKey.RevokedAt = &revokedAt
+
+	return s.r.Update(ctx, apiKey)
+}
+
+func (s *apiKeyServiceImpl) RotateApiKey(ctx context.Context, apiKeyId uint, ttl time.Duration) (*ApiKey, string, error) {
+	// call Get() to find the ApiKey and check Error
+	apiKey, err := s.r.Get(ctx, apiKeyId)
+	if err != nil {
+		return &ApiKey{}, "", err
+	}
+	if apiKey.RevokedAt != nil {
+		return &ApiKey{}, "", ErrApiKeyRevoked
+	}
+
+	// a new prefix and secret replace the old ones, so the old key stops working immediately
+	rawKey, err := s.assignSecret(apiKey, ttl)
+	if err != nil {
+		return &ApiKey{}, "", err
+	}
+	apiKey.LastUsedAt = nil
+
+	if err := s.r.Update(ctx, apiKey); err != nil {
+		return &ApiKey{}, "", err
+	}
+
+	return apiKey, rawKey, nil
+}
+
+func (s *apiKeyServiceImpl) AuthenticateApiKey(ctx context.Context, rawKey string) (*Principal, error) {
+	// split the raw key into prefix and secret
+	parts := strings.Split(rawKey, "_")
+	if len(parts) != 3 || parts[0] != apiKeyMarker || parts[1] == "" || parts[2] == "" {
+		return nil, ErrInvalidApiKey
+	}
+
+	// call GetByPrefix() to find the ApiKey, every failure is reported as the same error
+	apiKey, err := s.r.GetByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, ErrInvalidApiKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hashApiKeySecret(parts[2])), []byte(apiKey.SecretHash)) != 1 {
+		return nil, ErrInvalidApiKey
+	}
+
+	now := s.now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt)) {
+		return nil, ErrInvalidApiKey
+	}
+
+	// last use is only written once per period to avoid a write on every request
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchPeriod {
+		if err := s.r.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Principal{
+		Subject:    fmt.Sprintf("apikey:%d", apiKey.ID),
+		TenantID:   apiKey.TenantID,
+		Scopes:     apiKey.Scopes,
+		AuthMethod: AuthMethodApiKey,
+	}, nil
+}
+
+// assignSecret generates a new prefix and secret for the ApiKey and returns the plain key
+func (s *apiKeyServiceImpl) assignSecret(apiKey *ApiKey, ttl time.Duration) (string, error) {
+	prefix, err := randomHex(apiKeyPrefixBytes)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomHex(apiKeySecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	apiKey.Prefix = prefix
+	apiKey.SecretHash = hashApiKeySecret(secret)
+	apiKey.ExpiresAt = nil
+	if ttl > 0 {
+		expiresAt := s.now().Add(ttl)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	return apiKeyMarker + "_" + prefix + "_" + secret, nil
+}
+
+// the secret has 256 bits of entropy, so a plain SHA-256 is enough to store it
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
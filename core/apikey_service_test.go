@@ -0,0 +1,286 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Mock implementation of ApiKeyRepository
+type mockApiKeyRepo struct {
+	saveFunc          func(apiKey *ApiKey) error
+	getFunc           func(apiKeyId uint) (*ApiKey, error)
+	getByPrefixFunc   func(prefix string) (*ApiKey, error)
+	getAllFunc        func() ([]ApiKey, error)
+	updateFunc        func(apiKey *ApiKey) error
+	touchLastUsedFunc func(apiKeyId uint, usedAt time.Time) error
+}
+
+func (m *mockApiKeyRepo) Save(ctx context.Context, apiKey *ApiKey) error {
+	return m.saveFunc(apiKey)
+}
+
+func (m *mockApiKeyRepo) Get(ctx context.Context, apiKeyId uint) (*ApiKey, error) {
+	return m.getFunc(apiKeyId)
+}
+
+func (m *mockApiKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*ApiKey, error) {
+	return m.getByPrefixFunc(prefix)
+}
+
+func (m *mockApiKeyRepo) GetAll(ctx context.Context) ([]ApiKey, error) {
+	return m.getAllFunc()
+}
+
+func (m *mockApiKeyRepo) Update(ctx context.Context, apiKey *ApiKey) error {
+	return m.updateFunc(apiKey)
+}
+
+func (m *mockApiKeyRepo) TouchLastUsed(ctx context.Context, apiKeyId uint, usedAt time.Time) error {
+	return m.touchLastUsedFunc(apiKeyId, usedAt)
+}
+
+// issueTestApiKey issues a key through the service and returns the stored ApiKey with the plain key
+func issueTestApiKey(t *testing.T, ttl time.Duration) (*ApiKey, string) {
+	var stored *ApiKey
+	repo := &mockApiKeyRepo{
+		saveFunc: func(apiKey *ApiKey) error {
+			apiKey.ID = 7
+			stored = apiKey
+			return nil
+		},
+	}
+	_, rawKey, err := NewApiKeyService(repo).IssueApiKey(WithTenant(context.Background(), "bank-a"), "batch partner", []string{"customers:read"}, ttl)
+	assert.NoError(t, err)
+	return stored, rawKey
+}
+
+func TestIssueApiKey(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		apiKey, rawKey := issueTestApiKey(t, time.Hour)
+
+		// the plain key is never stored
+		assert.True(t, strings.HasPrefix(rawKey, "itmx_"+apiKey.Prefix+"_"))
+		assert.NotContains(t, apiKey.SecretHash, strings.Split(rawKey, "_")[2])
+		assert.Equal(t, []string{"customers:read"}, apiKey.Scopes)
+		assert.NotNil(t, apiKey.ExpiresAt)
+		// the key belongs to the tenant of the caller
+		assert.Equal(t, "bank-a", apiKey.TenantID)
+	})
+
+	t.Run("successful without expiry", func(t *testing.T) {
+		apiKey, _ := issueTestApiKey(t, 0)
+		assert.Nil(t, apiKey.ExpiresAt)
+	})
+
+	// Failure case
+	t.Run("(fail) name must not be empty", func(t *testing.T) {
+		service := NewApiKeyService(&mockApiKeyRepo{})
+
+		_, _, err := service.IssueApiKey(WithTenant(context.Background(), "bank-a"), " ", nil, 0)
+		assert.ErrorIs(t, err, ErrApiKeyNameEmpty)
+	})
+
+	t.Run("(fail) database error", func(t *testing.T) {
+		repo := &mockApiKeyRepo{
+			saveFunc: func(apiKey *ApiKey) error {
+				// Simulate Failure
+				return errors.New("database error")
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, rawKey, err := service.IssueApiKey(WithTenant(context.Background(), "bank-a"), "batch partner", nil, 0)
+		assert.Error(t, err)
+		assert.Equal(t, "", rawKey)
+	})
+}
+
+func TestAuthenticateApiKey(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		apiKey, rawKey := issueTestApiKey(t, time.Hour)
+		var touched uint
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				assert.Equal(t, apiKey.Prefix, prefix)
+				return apiKey, nil
+			},
+			touchLastUsedFunc: func(apiKeyId uint, usedAt time.Time) error {
+				touched = apiKeyId
+				return nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		principal, err := service.AuthenticateApiKey(context.Background(), rawKey)
+		assert.NoError(t, err)
+		assert.Equal(t, "apikey:7", principal.Subject)
+		assert.Equal(t, "bank-a", principal.TenantID)
+		assert.Equal(t, AuthMethodApiKey, principal.AuthMethod)
+		assert.Equal(t, []string{"customers:read"}, principal.Scopes)
+		assert.Equal(t, uint(7), touched)
+	})
+
+	t.Run("successful without touching a recently used key", func(t *testing.T) {
+		apiKey, rawKey := issueTestApiKey(t, 0)
+		lastUsedAt := time.Now()
+		apiKey.LastUsedAt = &lastUsedAt
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				return apiKey, nil
+			},
+			touchLastUsedFunc: func(apiKeyId uint, usedAt time.Time) error {
+				t.Fatal("last use must not be written again")
+				return nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, err := service.AuthenticateApiKey(context.Background(), rawKey)
+		assert.NoError(t, err)
+	})
+
+	// Failure case
+	t.Run("(fail) malformed key", func(t *testing.T) {
+		service := NewApiKeyService(&mockApiKeyRepo{})
+
+		_, err := service.AuthenticateApiKey(context.Background(), "not-a-key")
+		assert.ErrorIs(t, err, ErrInvalidApiKey)
+	})
+
+	t.Run("(fail) wrong secret", func(t *testing.T) {
+		apiKey, _ := issueTestApiKey(t, 0)
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				return apiKey, nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, err := service.AuthenticateApiKey(context.Background(), "itmx_"+apiKey.Prefix+"_wrong")
+		assert.ErrorIs(t, err, ErrInvalidApiKey)
+	})
+
+	t.Run("(fail) unknown prefix", func(t *testing.T) {
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				return &ApiKey{}, errors.New("record not found")
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, err := service.AuthenticateApiKey(context.Background(), "itmx_abc_def")
+		assert.ErrorIs(t, err, ErrInvalidApiKey)
+	})
+
+	t.Run("(fail) expired key", func(t *testing.T) {
+		apiKey, rawKey := issueTestApiKey(t, time.Hour)
+		expiredAt := time.Now().Add(-time.Second)
+		apiKey.ExpiresAt = &expiredAt
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				return apiKey, nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, err := service.AuthenticateApiKey(context.Background(), rawKey)
+		assert.ErrorIs(t, err, ErrInvalidApiKey)
+	})
+
+	t.Run("(fail) revoked key", func(t *testing.T) {
+		apiKey, rawKey := issueTestApiKey(t, 0)
+		revokedAt := time.Now()
+		apiKey.RevokedAt = &revokedAt
+		repo := &mockApiKeyRepo{
+			getByPrefixFunc: func(prefix string) (*ApiKey, error) {
+				return apiKey, nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, err := service.AuthenticateApiKey(context.Background(), rawKey)
+		assert.ErrorIs(t, err, ErrInvalidApiKey)
+	})
+}
+
+func TestRevokeApiKey(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		apiKey, _ := issueTestApiKey(t, 0)
+		repo := &mockApiKeyRepo{
+			getFunc: func(apiKeyId uint) (*ApiKey, error) {
+				return apiKey, nil
+			},
+			updateFunc: func(updated *ApiKey) error {
+				assert.NotNil(t, updated.RevokedAt)
+				return nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		err := service.RevokeApiKey(context.Background(), apiKey.ID)
+		assert.NoError(t, err)
+	})
+
+	// Failure case
+	t.Run("(fail) database error", func(t *testing.T) {
+		repo := &mockApiKeyRepo{
+			getFunc: func(apiKeyId uint) (*ApiKey, error) {
+				// Simulate Failure
+				return &ApiKey{}, errors.New("database error")
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		err := service.RevokeApiKey(context.Background(), 1)
+		assert.Error(t, err)
+		assert.Equal(t, "database error", err.Error())
+	})
+}
+
+func TestRotateApiKey(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		apiKey, oldRawKey := issueTestApiKey(t, 0)
+		oldPrefix := apiKey.Prefix
+		repo := &mockApiKeyRepo{
+			getFunc: func(apiKeyId uint) (*ApiKey, error) {
+				return apiKey, nil
+			},
+			updateFunc: func(updated *ApiKey) error {
+				return nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		rotated, rawKey, err := service.RotateApiKey(context.Background(), apiKey.ID, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), rotated.ID)
+		assert.NotEqual(t, oldRawKey, rawKey)
+		assert.NotEqual(t, oldPrefix, rotated.Prefix)
+		assert.NotNil(t, rotated.ExpiresAt)
+	})
+
+	// Failure case
+	t.Run("(fail) revoked key", func(t *testing.T) {
+		apiKey, _ := issueTestApiKey(t, 0)
+		revokedAt := time.Now()
+		apiKey.RevokedAt = &revokedAt
+		repo := &mockApiKeyRepo{
+			getFunc: func(apiKeyId uint) (*ApiKey, error) {
+				return apiKey, nil
+			},
+		}
+		service := NewApiKeyService(repo)
+
+		_, _, err := service.RotateApiKey(context.Background(), apiKey.ID, 0)
+		assert.ErrorIs(t, err, ErrApiKeyRevoked)
+	})
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionCustomerCreated = "customer.created"
+	AuditActionCustomerUpdated = "customer.updated"
+	AuditActionCustomerDeleted = "customer.deleted"
+)
+
+// AuditEntry records who changed which customer, it is written in the same transaction as the change
+type AuditEntry struct {
+	ID         uint      `gorm:"primaryKey"`
+	TenantID   string    `gorm:"not null;default:'';index"`
+	Actor      string    `gorm:"not null;default:''"`
+	Action     string    `gorm:"not null"`
+	CustomerID uint      `gorm:"index"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+//* Secondary Port (audit.go)
+
+// Entries are appended to the tenant of ctx (see TenantFromContext)
+type AuditRepository interface {
+	Append(ctx context.Context, entry *AuditEntry) error // Port
+}
+
+// newAuditEntry records action on the customer by the principal of ctx, the actor is empty without principal
+func newAuditEntry(ctx context.Context, action string, customerId uint) *AuditEntry {
+	entry := &AuditEntry{Action: action, CustomerID: customerId}
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		entry.Actor = principal.Subject
+	}
+	return entry
+}
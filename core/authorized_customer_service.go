@@ -0,0 +1,191 @@
+package core
+
+import "context"
+
+// RedactedName replaces the name of customers read by principals without pii:read
+const RedactedName = "***"
+
+// authorizedCustomerService checks the policy before every operation of the wrapped CustomerService
+type authorizedCustomerService struct {
+	next   CustomerService
+	policy *Policy
+}
+
+func NewAuthorizedCustomerService(service CustomerService, policy *Policy) CustomerService {
+	return &authorizedCustomerService{next: service, policy: policy}
+}
+
+func (s *authorizedCustomerService) CreateCustomer(ctx context.Context, customer Customer) error {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersWrite); err != nil {
+		return err
+	}
+
+	return s.next.CreateCustomer(ctx, customer)
+}
+
+func (s *authorizedCustomerService) GetCustomerById(ctx context.Context, customerId uint) (*Customer, error) {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return &Customer{}, err
+	}
+
+	customer, err := s.next.GetCustomerById(ctx, customerId)
+	if err != nil {
+		return customer, err
+	}
+
+	return s.redact(ctx, customer), nil
+}
+
+func (s *authorizedCustomerService) GetAllCustomer(ctx context.Context) ([]Customer, error) {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return []Customer{}, err
+	}
+
+	customers, err := s.next.GetAllCustomer(ctx)
+	if err != nil {
+		return customers, err
+	}
+
+	for index := range customers {
+		customers[index] = *s.redact(ctx, &customers[index])
+	}
+	return customers, nil
+}
+
+func (s *authorizedCustomerService) ExportCustomers(ctx context.Context) (CustomerStream, error) {
+	// Check permission before the stream starts
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return nil, err
+	}
+
+	stream, err := s.next.ExportCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(fn func(customers []Customer) error) error {
+		return stream(func(customers []Customer) error {
+			for index := range customers {
+				customers[index] = *s.redact(ctx, &customers[index])
+			}
+			return fn(customers)
+		})
+	}, nil
+}
+
+func (s *authorizedCustomerService) UpdateCustomer(ctx context.Context, customerId uint, customer *Customer) (*Customer, error) {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersWrite); err != nil {
+		return &Customer{}, err
+	}
+
+	updatedCustomer, err := s.next.UpdateCustomer(ctx, customerId, customer)
+	if err != nil {
+		return updatedCustomer, err
+	}
+
+	return s.redact(ctx, updatedCustomer), nil
+}
+
+func (s *authorizedCustomerService) DeleteCustomer(ctx context.Context, customerId uint) error {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersDelete); err != nil {
+		return err
+	}
+
+	return s.next.DeleteCustomer(ctx, customerId)
+}
+
+func (s *authorizedCustomerService) SearchCustomerById(ctx context.Context, customerId uint) error {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return err
+	}
+
+	return s.next.SearchCustomerById(ctx, customerId)
+}
+
+func (s *authorizedCustomerService) ExecuteBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchResult, error) {
+	// Check the permission of every kind of operation in the batch
+	for _, operation := range operations {
+		permission := PermissionCustomersWrite
+		if operation.Operation == BatchOperationDelete {
+			permission = PermissionCustomersDelete
+		}
+		if err := s.policy.Authorize(ctx, permission); err != nil {
+			return []BatchResult{}, err
+		}
+	}
+
+	return s.next.ExecuteBatch(ctx, operations, atomic)
+}
+
+func (s *authorizedCustomerService) ImportCustomers(ctx context.Context, source CustomerSource, dryRun bool) (*ImportReport, error) {
+	// Check permission, a dry run needs it too as it tells which rows would be written
+	if err := s.policy.Authorize(ctx, PermissionCustomersWrite); err != nil {
+		return &ImportReport{}, err
+	}
+
+	return s.next.ImportCustomers(ctx, source, dryRun)
+}
+
+func (s *authorizedCustomerService) StreamCustomerEvents(ctx context.Context, lastEventId *uint) (CustomerEventStream, error) {
+	// Check permission before the stream starts
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return nil, err
+	}
+
+	stream, err := s.next.StreamCustomerEvents(ctx, lastEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(fn func(events []StreamedEvent) error) error {
+		return stream(func(events []StreamedEvent) error {
+			for index := range events {
+				if events[index].Event.Customer != nil {
+					events[index].Event.Customer = s.redact(ctx, events[index].Event.Customer)
+				}
+			}
+			return fn(events)
+		})
+	}, nil
+}
+
+func (s *authorizedCustomerService) GetChanges(ctx context.Context, since uint, limit int) ([]ChangeRecord, error) {
+	// Check permission
+	if err := s.policy.Authorize(ctx, PermissionCustomersRead); err != nil {
+		return []ChangeRecord{}, err
+	}
+
+	changes, err := s.next.GetChanges(ctx, since, limit)
+	if err != nil {
+		return changes, err
+	}
+
+	for index := range changes {
+		if changes[index].Customer != nil {
+			changes[index].Customer = s.redact(ctx, changes[index].Customer)
+		}
+	}
+	return changes, nil
+}
+
+func (s *authorizedCustomerService) ValidateName(customerName string) error {
+	// validation reads no data, so it needs no permission
+	return s.next.ValidateName(customerName)
+}
+
+// redact hides personal data from principals without pii:read, the given customer is not modified
+func (s *authorizedCustomerService) redact(ctx context.Context, customer *Customer) *Customer {
+	if principal, ok := PrincipalFromContext(ctx); ok && s.policy.Allows(principal, PermissionPIIRead) {
+		return customer
+	}
+
+	redacted := *customer
+	redacted.Name = RedactedName
+	return &redacted
+}
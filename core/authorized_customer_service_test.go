@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *Policy {
+	return NewPolicy(map[string][]string{
+		"admin":   {PermissionCustomersRead, PermissionCustomersWrite, PermissionCustomersDelete, PermissionPIIRead},
+		"auditor": {PermissionCustomersRead},
+	})
+}
+
+func contextWith(roles []string, scopes []string) context.Context {
+	return WithPrincipal(context.Background(), &Principal{Subject: "user-1", Roles: roles, Scopes: scopes})
+}
+
+func setupAuthorizedService() (CustomerService, *bool) {
+	deleted := false
+	repo := &mockCustomerRepo{
+		saveFunc: func(customer Customer) error {
+			return nil
+		},
+		getFunc: func(customerId uint) (*Customer, error) {
+			return &Customer{ID: customerId, Name: "Fiat", Age: uint(24)}, nil
+		},
+		getAllFunc: func() ([]Customer, error) {
+			return []Customer{{ID: uint(1), Name: "Fiat", Age: uint(24)}}, nil
+		},
+		forEachBatchFunc: func(batchSize int, fn func([]Customer) error) error {
+			return fn([]Customer{{ID: uint(1), Name: "Fiat", Age: uint(24)}})
+		},
+		deleteFunc: func(customerId uint) error {
+			deleted = true
+			return nil
+		},
+		changesFunc: func(since uint, limit int) ([]ChangeRecord, error) {
+			return []ChangeRecord{{Sequence: 1, Operation: ChangeCreate, CustomerID: 1, Customer: &Customer{ID: uint(1), Name: "Fiat", Age: uint(24)}}}, nil
+		},
+	}
+	return NewAuthorizedCustomerService(NewCustomerService(repo, newMockUnitOfWork(repo)), testPolicy()), &deleted
+}
+
+func TestAuthorizedCustomerService(t *testing.T) {
+	// Success case
+	t.Run("successful read with pii:read", func(t *testing.T) {
+		service, _ := setupAuthorizedService()
+
+		customer, err := service.GetCustomerById(contextWith([]string{"admin"}, nil), uint(1))
+		assert.NoError(t, err)
+		assert.Equal(t, "Fiat", customer.Name)
+	})
+
+	t.Run("successful read without pii:read is redacted", func(t *testing.T) {
+		service, _ := setupAuthorizedService()
+
+		customer, err := service.GetCustomerById(contextWith([]string{"auditor"}, nil), uint(1))
+		assert.NoError(t, err)
+		assert.Equal(t, RedactedName, customer.Name)
+		assert.Equal(t, uint(24), customer.Age)
+
+		customers, err := service.GetAllCustomer(contextWith([]string{"auditor"}, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, RedactedName, customers[0].Name)
+
+		stream, err := service.ExportCustomers(contextWith([]string{"auditor"}, nil))
+		assert.NoError(t, err)
+		assert.NoError(t, stream(func(customers []Customer) error {
+			assert.Equal(t, RedactedName, customers[0].Name)
+			return nil
+		}))
+
+		lastEventId := uint(0)
+		events, err := service.StreamCustomerEvents(contextWith([]string{"auditor"}, nil), &lastEventId)
+		assert.NoError(t, err)
+		assert.Equal(t, "client gone", events(func(events []StreamedEvent) error {
+			assert.Equal(t, RedactedName, events[0].Event.Customer.Name)
+			return errors.New("client gone")
+		}).Error())
+	})
+
+	t.Run("successful with a directly granted scope", func(t *testing.T) {
+		service, deleted := setupAuthorizedService()
+
+		err := service.DeleteCustomer(contextWith(nil, []string{PermissionCustomersDelete}), uint(1))
+		assert.NoError(t, err)
+		assert.True(t, *deleted)
+	})
+
+	// Failure case
+	t.Run("(fail) delete without customers:delete", func(t *testing.T) {
+		service, deleted := setupAuthorizedService()
+
+		err := service.DeleteCustomer(contextWith([]string{"auditor"}, nil), uint(1))
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.Equal(t, "forbidden: missing permission customers:delete", err.Error())
+		assert.False(t, *deleted)
+	})
+
+	t.Run("(fail) create without customers:write", func(t *testing.T) {
+		service, _ := setupAuthorizedService()
+
+		err := service.CreateCustomer(contextWith([]string{"auditor"}, nil), Customer{Name: "Fiat", Age: uint(24)})
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("(fail) unknown role", func(t *testing.T) {
+		service, _ := setupAuthorizedService()
+
+		_, err := service.GetAllCustomer(contextWith([]string{"guest"}, nil))
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("(fail) anonymous call", func(t *testing.T) {
+		service, _ := setupAuthorizedService()
+
+		err := service.SearchCustomerById(context.Background(), uint(1))
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+}
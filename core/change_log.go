@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operations of a change record
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Page sizes of the change feed
+const (
+	DefaultChangeLimit = 100
+	MaxChangeLimit     = 1000
+)
+
+var ErrInvalidChangeLimit = errors.New("limit must be between 1 and 1000")
+
+// ChangeRecord is one mutation of a customer written by the repository in the same transaction as the mutation.
+// Sequence grows with every change and is given out in commit order: a record only becomes visible after every
+// record with a smaller Sequence, so "all changes since X" is every record of the tenant with a greater Sequence
+// and a reader resuming after X never misses a change that commits later. Numbers of rolled back transactions are
+// not reused, so there can be gaps. Customer is the state after the change, a delete leaves a tombstone without it
+type ChangeRecord struct {
+	Sequence   uint      `gorm:"primaryKey;autoIncrement:false;index:idx_change_records_tenant_sequence,priority:2" json:"seq"`
+	TenantID   string    `gorm:"not null;default:'';index:idx_change_records_tenant_sequence,priority:1" json:"-"`
+	Operation  string    `gorm:"not null" json:"op"`
+	CustomerID uint      `gorm:"not null" json:"customer_id"`
+	Customer   *Customer `gorm:"serializer:json" json:"customer,omitempty"`
+	Deleted    bool      `gorm:"not null;default:false" json:"deleted"`
+	ChangedAt  time.Time `gorm:"not null" json:"changed_at"`
+}
+
+// NewChangeRecord records the change of the customer in the tenant of ctx, customer is nil for a delete
+func NewChangeRecord(ctx context.Context, operation string, customerId uint, customer *Customer) *ChangeRecord {
+	return &ChangeRecord{
+		TenantID:   TenantFromContext(ctx),
+		Operation:  operation,
+		CustomerID: customerId,
+		Customer:   customer,
+		Deleted:    operation == ChangeDelete,
+		ChangedAt:  time.Now().UTC(),
+	}
+}
+
+// changeEvents are the customer events of the operations of change records
+var changeEvents = map[string]string{
+	ChangeCreate: EventCustomerCreated,
+	ChangeUpdate: EventCustomerUpdated,
+	ChangeDelete: EventCustomerDeleted,
+}
+
+// Event is the customer event of the change, the same the outbox publishes for it
+func (c ChangeRecord) Event() CustomerEvent {
+	return CustomerEvent{
+		Type:       changeEvents[c.Operation],
+		TenantID:   c.TenantID,
+		CustomerID: c.CustomerID,
+		Customer:   c.Customer,
+		OccurredAt: c.ChangedAt,
+	}
+}
+
+func (s *customerServiceImpl) GetChanges(ctx context.Context, since uint, limit int) ([]ChangeRecord, error) {
+	// Business logic...
+	// Check limit, no limit gets the default page
+	if limit == 0 {
+		limit = DefaultChangeLimit
+	}
+	if limit < 0 || limit > MaxChangeLimit {
+		return []ChangeRecord{}, ErrInvalidChangeLimit
+	}
+
+	// call Changes() for get the changes after since from gorm adapter
+	changes, err := s.r.Changes(ctx, since, limit)
+	if err != nil {
+		return []ChangeRecord{}, err
+	}
+
+	return changes, nil
+}
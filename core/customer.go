@@ -1,7 +1,8 @@
 package core
 
 type Customer struct {
-	ID   uint `gorm:"primaryKey"`
-	Name string
-	Age  uint
+	ID       uint   `gorm:"primaryKey"`
+	TenantID string `gorm:"not null;default:'';uniqueIndex:idx_customers_tenant_name" json:"-"`
+	Name     string `gorm:"uniqueIndex:idx_customers_tenant_name"`
+	Age      uint
 }
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operations of a customer batch
+const (
+	BatchOperationCreate = "create"
+	BatchOperationUpdate = "update"
+	BatchOperationDelete = "delete"
+)
+
+// MaxBatchSize is the largest number of operations accepted in one batch
+const MaxBatchSize = 10000
+
+var (
+	ErrBatchEmpty            = errors.New("batch must contain at least one operation")
+	ErrBatchTooLarge         = fmt.Errorf("batch must not contain more than %d operations", MaxBatchSize)
+	ErrInvalidBatchOperation = errors.New("operation must be create, update or delete")
+	ErrBatchAborted          = errors.New("not applied, another operation of the batch failed")
+)
+
+// BatchOperation is one item of a batch, ID is used by update and delete and Customer by create and update
+type BatchOperation struct {
+	Operation string
+	ID        uint
+	Customer  Customer
+}
+
+// BatchResult is the outcome of the operation at Index, ID is the created, updated or deleted customer
+type BatchResult struct {
+	Index     int
+	Operation string
+	ID        uint
+	Err       error
+}
+
+// BatchItemError tells which operation made a repository batch fail
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
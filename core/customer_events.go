@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Defaults of the streams of StreamCustomerEvents
+const (
+	eventStreamBatchSize    = 100
+	eventStreamPollInterval = time.Second
+)
+
+// StreamedEvent is a customer event of the change log, ID is the sequence of its change record. Sequences become
+// visible in order, so a stream started after ID resumes where a client left off without missing an event
+type StreamedEvent struct {
+	ID    uint
+	Event CustomerEvent
+}
+
+// CustomerEventStream sends the customer events to fn in order as they happen, until ctx is canceled or fn returns
+// an error, which is returned. fn is also called without events after every poll that found none, so callers can
+// check the client is still there
+type CustomerEventStream func(fn func(events []StreamedEvent) error) error
+
+// StreamCustomerEvents streams the events after lastEventId, a nil lastEventId starts at the current head of the
+// change log, so a new client only gets the events that happen after it connected
+func (s *customerServiceImpl) StreamCustomerEvents(ctx context.Context, lastEventId *uint) (CustomerEventStream, error) {
+	// Business logic...
+	// the stream polls the change log of the tenant, so it sees the changes of every instance of the service
+	var since uint
+	if lastEventId != nil {
+		since = *lastEventId
+	} else {
+		// call ChangeHead() to find where a new stream starts in gorm adapter and check Error
+		head, err := s.r.ChangeHead(ctx)
+		if err != nil {
+			return nil, err
+		}
+		since = head
+	}
+
+	return func(fn func(events []StreamedEvent) error) error {
+		for {
+			// call Changes() to read the next events from gorm adapter and check Error
+			changes, err := s.r.Changes(ctx, since, eventStreamBatchSize)
+			if err != nil {
+				return err
+			}
+
+			events := make([]StreamedEvent, len(changes))
+			for index, change := range changes {
+				events[index] = StreamedEvent{ID: change.Sequence, Event: change.Event()}
+			}
+			if err := fn(events); err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				since = changes[len(changes)-1].Sequence
+			}
+
+			// read again right away while full batches come back, the client is not caught up yet
+			if len(changes) == eventStreamBatchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.pollInterval):
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// MaxImportErrors is the largest number of row errors kept in an ImportReport, the rest are only counted
+const MaxImportErrors = 1000
+
+var ErrDuplicateImportName = errors.New("name appears more than once in the import")
+
+// ImportRow is one row read by a CustomerSource, Err is set when the row could not be mapped to a Customer
+type ImportRow struct {
+	Line     int
+	Customer Customer
+	Err      error
+}
+
+// CustomerSource streams the rows of an import, Next returns io.EOF after the last row
+type CustomerSource interface {
+	Next() (ImportRow, error)
+}
+
+// ImportRowError is the reason the row at Line was not imported
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+// ImportReport summarizes an import, in a dry run Imported stays 0 and Valid tells what would be imported
+type ImportReport struct {
+	DryRun          bool
+	Rows            int
+	Valid           int
+	Imported        int
+	Failed          int
+	Errors          []ImportRowError
+	ErrorsTruncated bool
+}
+
+func (r *ImportReport) addError(line int, err error) {
+	r.Failed++
+	if len(r.Errors) >= MaxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportRowError{Line: line, Err: err})
+}
+
+func (s *customerServiceImpl) ImportCustomers(ctx context.Context, source CustomerSource, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Errors: []ImportRowError{}}
+	seen := make(map[string]bool)
+	var pending []BatchOperation
+	var pendingLines []int
+
+	// commit the pending rows as one best-effort batch, rows rejected by the repository are reported by line. A dry
+	// run only checks the names of the rows against the names already stored
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if dryRun {
+			err := s.checkImportNames(ctx, report, pending, pendingLines)
+			pending, pendingLines = pending[:0], pendingLines[:0]
+			return err
+		}
+		results, err := s.ExecuteBatch(ctx, pending, false)
+		if err != nil {
+			return err
+		}
+		for index, result := range results {
+			if result.Err != nil {
+				report.addError(pendingLines[index], result.Err)
+				continue
+			}
+			report.Imported++
+		}
+		pending, pendingLines = pending[:0], pendingLines[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		// call Next() to read the next row from the source and check Error
+		row, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Rows++
+
+		// Business logic...
+		// validate the row with the same rules as CreateCustomer
+		if err := s.validateImportRow(row, seen); err != nil {
+			report.addError(row.Line, err)
+			continue
+		}
+		report.Valid++
+
+		pending = append(pending, BatchOperation{Operation: BatchOperationCreate, Customer: row.Customer})
+		pendingLines = append(pendingLines, row.Line)
+		if len(pending) == batchChunkSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (s *customerServiceImpl) validateImportRow(row ImportRow, seen map[string]bool) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	if err := s.ValidateName(row.Customer.Name); err != nil {
+		return err
+	}
+	if row.Customer.Age == 0 {
+		return ErrInvalidAge
+	}
+
+	// Check name is unique inside the import, names already stored are checked batch by batch
+	if seen[row.Customer.Name] {
+		return ErrDuplicateImportName
+	}
+	seen[row.Customer.Name] = true
+
+	return nil
+}
+
+// checkImportNames reports the rows of a dry run whose name is already stored in the tenant, as committing them
+// would
+func (s *customerServiceImpl) checkImportNames(ctx context.Context, report *ImportReport, operations []BatchOperation, lines []int) error {
+	names := make([]string, len(operations))
+	for index, operation := range operations {
+		names[index] = operation.Customer.Name
+	}
+
+	// call ExistingNames() to get the names taken in the tenant from gorm adapter and check Error
+	existing, err := s.r.ExistingNames(ctx, names)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		taken[name] = true
+	}
+
+	for index, operation := range operations {
+		if taken[operation.Customer.Name] {
+			report.Valid--
+			report.addError(lines[index], ErrNameAlreadyExists)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,29 @@
 package core
 
+import "context"
+
 //* Secondary Port (customer_repository.go)
 
+// Every method is scoped to the tenant of ctx (see TenantFromContext)
 type CustomerRepository interface { // Spec
-	Save(customer Customer) error                                  // Port
-	Get(customerId uint) (*Customer, error)                        // Port
-	GetAll() ([]Customer, error)                                   // Port
-	Update(customerId uint, customer *Customer) (*Customer, error) // Port
-	Delete(customerId uint) error                                  // Port
-	Search(customerId uint) error                                  // Port
+	Save(ctx context.Context, customer *Customer) error                                 // Port
+	Get(ctx context.Context, customerId uint) (*Customer, error)                        // Port
+	GetAll(ctx context.Context) ([]Customer, error)                                     // Port
+	Update(ctx context.Context, customerId uint, customer *Customer) (*Customer, error) // Port
+	Delete(ctx context.Context, customerId uint) error                                  // Port
+	Search(ctx context.Context, customerId uint) error                                  // Port
+	// ForEachBatch passes the customers ordered by id to fn batchSize at a time, the slice is reused between
+	// calls and the first error of fn or of ctx stops the iteration and is returned. Every batch is a query of
+	// its own, so fn may modify the customers it gets, as retention jobs do
+	ForEachBatch(ctx context.Context, batchSize int, fn func(customers []Customer) error) error // Port
+	// ApplyBatch runs the operations in order in one transaction and returns the customer id of each operation,
+	// a failing operation rolls back the whole batch and is reported as *BatchItemError
+	ApplyBatch(ctx context.Context, operations []BatchOperation) ([]uint, error) // Port
+	// Changes returns up to limit change records with a sequence after since, ordered by sequence. Every
+	// mutation of the methods above writes its change record in the same transaction
+	Changes(ctx context.Context, since uint, limit int) ([]ChangeRecord, error) // Port
+	// ChangeHead returns the greatest sequence of the change records of the tenant, 0 without any
+	ChangeHead(ctx context.Context) (uint, error) // Port
+	// ExistingNames returns the names among names that customers of the tenant already have
+	ExistingNames(ctx context.Context, names []string) ([]string, error) // Port
 }
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SeedReport counts the customers of a seed, Existing ones were created by an earlier run and left as they are
+type SeedReport struct {
+	Created  int
+	Existing int
+}
+
+// SeedCustomers creates the customers through service in the tenant of ctx. A customer whose name exists in the
+// tenant is skipped, so running the same fixtures again changes nothing. The first invalid customer stops the seed
+func SeedCustomers(ctx context.Context, service CustomerService, customers []Customer) (*SeedReport, error) {
+	report := &SeedReport{}
+	for _, customer := range customers {
+		// Validate name in service and check Error
+		if err := service.ValidateName(customer.Name); err != nil {
+			return report, fmt.Errorf("customer %q: %w", customer.Name, err)
+		}
+
+		// call CreateCustomer() to create the Customer in service, an existing name is not an error
+		err := service.CreateCustomer(ctx, customer)
+		if errors.Is(err, ErrNameAlreadyExists) {
+			report.Existing++
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("customer %q: %w", customer.Name, err)
+		}
+		report.Created++
+	}
+
+	return report, nil
+}
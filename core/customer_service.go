@@ -1,54 +1,66 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"regexp"
+	"time"
 )
 
 // ! Primary Port (customer_service.go)
 type CustomerService interface {
-	CreateCustomer(customer Customer) error
-	GetCustomerById(customerId uint) (*Customer, error)
-	GetAllCustomer() ([]Customer, error)
-	UpdateCustomer(customerId uint, customer *Customer) (*Customer, error)
-	DeleteCustomer(customerId uint) error
-	SearchCustomerById(customerId uint) error
+	CreateCustomer(ctx context.Context, customer Customer) error
+	GetCustomerById(ctx context.Context, customerId uint) (*Customer, error)
+	GetAllCustomer(ctx context.Context) ([]Customer, error)
+	ExportCustomers(ctx context.Context) (CustomerStream, error)
+	UpdateCustomer(ctx context.Context, customerId uint, customer *Customer) (*Customer, error)
+	DeleteCustomer(ctx context.Context, customerId uint) error
+	SearchCustomerById(ctx context.Context, customerId uint) error
 	ValidateName(customerName string) error
+	ExecuteBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchResult, error)
+	ImportCustomers(ctx context.Context, source CustomerSource, dryRun bool) (*ImportReport, error)
+	StreamCustomerEvents(ctx context.Context, lastEventId *uint) (CustomerEventStream, error)
+	GetChanges(ctx context.Context, since uint, limit int) ([]ChangeRecord, error)
 }
 
 // Implement CustomerRepository
 type customerServiceImpl struct {
-	r CustomerRepository
+	r            CustomerRepository
+	uow          UnitOfWork
+	pollInterval time.Duration
 }
 
-func NewCustomerService(repo CustomerRepository) CustomerService {
-	return &customerServiceImpl{r: repo}
+// NewCustomerService reads from repo, changes run in a transaction of uow together with their audit entry
+func NewCustomerService(repo CustomerRepository, uow UnitOfWork) CustomerService {
+	return &customerServiceImpl{r: repo, uow: uow, pollInterval: eventStreamPollInterval}
 }
 
-func (s *customerServiceImpl) CreateCustomer(customer Customer) error {
+func (s *customerServiceImpl) CreateCustomer(ctx context.Context, customer Customer) error {
 	// Business logic...
 	// Check Age
 	if customer.Age == 0 {
-		return errors.New("age must more than 0")
+		return ErrInvalidAge
 	}
 
-	// call Save() to pass agreement value of Customer for insert in gorm adapter
-	if err := s.r.Save(customer); err != nil {
-		return err
-	}
-
-	return nil
+	// call Save() to pass agreement value of Customer for insert in gorm adapter, its audit entry and event are
+	// written in the same transaction
+	return s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		if err := repos.Customers.Save(ctx, &customer); err != nil {
+			return err
+		}
+		return recordChange(ctx, repos, EventCustomerCreated, customer.ID, &customer)
+	})
 }
 
-func (s *customerServiceImpl) GetCustomerById(customerId uint) (*Customer, error) {
+func (s *customerServiceImpl) GetCustomerById(ctx context.Context, customerId uint) (*Customer, error) {
 	// Business logic...
 	// Check customerId
 	if customerId == 0 {
-		return &Customer{}, errors.New("customerId must more than 0")
+		return &Customer{}, ErrInvalidCustomerId
 	}
 
 	// call Get() to pass agreement customerId for get a Customer from gorm adapter
-	customer, err := s.r.Get(customerId)
+	customer, err := s.r.Get(ctx, customerId)
 	if err != nil {
 		return &Customer{}, err
 	}
@@ -56,12 +68,12 @@ func (s *customerServiceImpl) GetCustomerById(customerId uint) (*Customer, error
 	return customer, nil
 }
 
-func (s *customerServiceImpl) GetAllCustomer() ([]Customer, error) {
+func (s *customerServiceImpl) GetAllCustomer(ctx context.Context) ([]Customer, error) {
 	// Business logic...
 	var customers []Customer
 
 	// call GetAll() for get all Customers from gorm adapter
-	customers, err := s.r.GetAll()
+	customers, err := s.r.GetAll(ctx)
 	if err != nil {
 		return []Customer{}, err
 	}
@@ -69,42 +81,66 @@ func (s *customerServiceImpl) GetAllCustomer() ([]Customer, error) {
 	return customers, nil
 }
 
-func (s *customerServiceImpl) UpdateCustomer(customerId uint, customer *Customer) (*Customer, error) {
+// exportBatchSize is the number of customers read from the repository at once by an export
+const exportBatchSize = 1000
+
+// CustomerStream sends all customers to fn batch by batch, the first error of fn stops the stream and is returned
+type CustomerStream func(fn func(customers []Customer) error) error
+
+func (s *customerServiceImpl) ExportCustomers(ctx context.Context) (CustomerStream, error) {
+	// Business logic...
+	// the stream reads from the repository only when it is run, so exports keep a constant memory
+	return func(fn func(customers []Customer) error) error {
+		// call ForEachBatch() to read the customers from gorm adapter page by page
+		return s.r.ForEachBatch(ctx, exportBatchSize, fn)
+	}, nil
+}
+
+func (s *customerServiceImpl) UpdateCustomer(ctx context.Context, customerId uint, customer *Customer) (*Customer, error) {
 	// Business logic...
 	// Check Age
 	if customer.Age == 0 {
-		return &Customer{}, errors.New("age must more than 0")
+		return &Customer{}, ErrInvalidAge
 	}
 
 	// call Update() to pass agreement customerId and Customer for update a customer in gorm adapter and return value updated
-	customer, err := s.r.Update(customerId, customer)
+	var updatedCustomer *Customer
+	err := s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if updatedCustomer, err = repos.Customers.Update(ctx, customerId, customer); err != nil {
+			return err
+		}
+		return recordChange(ctx, repos, EventCustomerUpdated, customerId, updatedCustomer)
+	})
 
 	if err != nil {
 		return &Customer{}, err
 	}
 
-	return customer, nil
+	return updatedCustomer, nil
 }
 
-func (s *customerServiceImpl) DeleteCustomer(customerId uint) error {
+func (s *customerServiceImpl) DeleteCustomer(ctx context.Context, customerId uint) error {
 	// Business logic...
-	// call Delete() to pass agreement customerId for delete a customer in gorm adapter
-	if err := s.r.Delete(customerId); err != nil {
-		return err
-	}
-
-	return nil
+	// call Delete() to pass agreement customerId for delete a customer in gorm adapter with its audit entry and event,
+	// a missing customer fails the delete so no change is recorded for it
+	return s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		if err := repos.Customers.Delete(ctx, customerId); err != nil {
+			return err
+		}
+		return recordChange(ctx, repos, EventCustomerDeleted, customerId, nil)
+	})
 }
 
-func (s *customerServiceImpl) SearchCustomerById(customerId uint) error {
+func (s *customerServiceImpl) SearchCustomerById(ctx context.Context, customerId uint) error {
 	// Business logic...
 	// Check customerId
 	if customerId == 0 {
-		return errors.New("customerId must more than 0")
+		return ErrInvalidCustomerId
 	}
 
 	// call Search() to pass agreement customerId for search a customer in gorm adapter
-	if err := s.r.Search(customerId); err != nil {
+	if err := s.r.Search(ctx, customerId); err != nil {
 		return err
 	}
 
@@ -117,6 +153,12 @@ var (
 	NameRegex      = `^[a-zA-Z\s]+$`
 )
 
+var (
+	ErrInvalidAge        = errors.New("age must more than 0")
+	ErrInvalidCustomerId = errors.New("customerId must more than 0")
+	ErrNameAlreadyExists = errors.New("name already exists")
+)
+
 func (s *customerServiceImpl) ValidateName(customerName string) error {
 	// Validate name and check
 	matched, err := regexp.MatchString(NameRegex, customerName)
@@ -128,3 +170,180 @@ func (s *customerServiceImpl) ValidateName(customerName string) error {
 	}
 	return nil
 }
+
+// batchChunkSize is the number of operations a best-effort batch tries to apply at once
+const batchChunkSize = 500
+
+func (s *customerServiceImpl) ExecuteBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchResult, error) {
+	// Business logic...
+	// Check batch size
+	if len(operations) == 0 {
+		return []BatchResult{}, ErrBatchEmpty
+	}
+	if len(operations) > MaxBatchSize {
+		return []BatchResult{}, ErrBatchTooLarge
+	}
+
+	// validate every operation with the same rules as the single customer operations
+	results := make([]BatchResult, len(operations))
+	var valid []int
+	for index, operation := range operations {
+		results[index] = BatchResult{Index: index, Operation: operation.Operation, ID: operation.ID}
+		if err := s.validateBatchOperation(operation); err != nil {
+			results[index].Err = err
+			continue
+		}
+		valid = append(valid, index)
+	}
+
+	if atomic {
+		return s.applyAtomic(ctx, operations, results, len(valid) == len(operations))
+	}
+
+	// best effort: apply the valid operations chunk by chunk, a failing chunk is retried one by one
+	for start := 0; start < len(valid); start += batchChunkSize {
+		chunk := valid[start:min(start+batchChunkSize, len(valid))]
+		chunkOperations := make([]BatchOperation, len(chunk))
+		for position, index := range chunk {
+			chunkOperations[position] = operations[index]
+		}
+
+		// call ApplyBatch() to apply the chunk in gorm adapter
+		ids, err := s.applyBatch(ctx, chunkOperations)
+		if err == nil {
+			for position, index := range chunk {
+				results[index].ID = ids[position]
+			}
+			continue
+		}
+
+		for _, index := range chunk {
+			ids, err := s.applyBatch(ctx, []BatchOperation{operations[index]})
+			if err != nil {
+				results[index].Err = unwrapBatchItemError(err)
+				continue
+			}
+			results[index].ID = ids[0]
+		}
+	}
+
+	return results, nil
+}
+
+// applyAtomic applies every operation or none of them
+func (s *customerServiceImpl) applyAtomic(ctx context.Context, operations []BatchOperation, results []BatchResult, allValid bool) ([]BatchResult, error) {
+	if !allValid {
+		abortOthers(results)
+		return results, nil
+	}
+
+	// call ApplyBatch() to apply the batch in one transaction in gorm adapter
+	ids, err := s.applyBatch(ctx, operations)
+	if err != nil {
+		var itemErr *BatchItemError
+		if !errors.As(err, &itemErr) || itemErr.Index < 0 || itemErr.Index >= len(results) {
+			return []BatchResult{}, err
+		}
+		results[itemErr.Index].Err = itemErr.Err
+		abortOthers(results)
+		return results, nil
+	}
+
+	for index := range results {
+		results[index].ID = ids[index]
+	}
+	return results, nil
+}
+
+// batchEvents is the event of every batch operation
+var batchEvents = map[string]string{
+	BatchOperationCreate: EventCustomerCreated,
+	BatchOperationUpdate: EventCustomerUpdated,
+	BatchOperationDelete: EventCustomerDeleted,
+}
+
+// applyBatch applies the operations and records each of them in one transaction
+func (s *customerServiceImpl) applyBatch(ctx context.Context, operations []BatchOperation) ([]uint, error) {
+	var ids []uint
+	err := s.uow.Do(ctx, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if ids, err = repos.Customers.ApplyBatch(ctx, operations); err != nil {
+			return err
+		}
+		for index, operation := range operations {
+			var customer *Customer
+			if operation.Operation != BatchOperationDelete {
+				customer = &Customer{ID: ids[index], Name: operation.Customer.Name, Age: operation.Customer.Age}
+			}
+			if err := recordChange(ctx, repos, batchEvents[operation.Operation], ids[index], customer); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []uint{}, err
+	}
+
+	return ids, nil
+}
+
+func (s *customerServiceImpl) validateBatchOperation(operation BatchOperation) error {
+	switch operation.Operation {
+	case BatchOperationCreate, BatchOperationUpdate:
+		if operation.Operation == BatchOperationUpdate && operation.ID == 0 {
+			return ErrInvalidCustomerId
+		}
+		if err := s.ValidateName(operation.Customer.Name); err != nil {
+			return err
+		}
+		if operation.Customer.Age == 0 {
+			return ErrInvalidAge
+		}
+	case BatchOperationDelete:
+		if operation.ID == 0 {
+			return ErrInvalidCustomerId
+		}
+	default:
+		return ErrInvalidBatchOperation
+	}
+
+	return nil
+}
+
+// abortOthers marks every operation without an error of its own as not applied
+func abortOthers(results []BatchResult) {
+	for index := range results {
+		if results[index].Err == nil {
+			results[index].Err = ErrBatchAborted
+		}
+	}
+}
+
+func unwrapBatchItemError(err error) error {
+	var itemErr *BatchItemError
+	if errors.As(err, &itemErr) {
+		return itemErr.Err
+	}
+	return err
+}
+
+// eventAuditActions is the audit action of every customer event
+var eventAuditActions = map[string]string{
+	EventCustomerCreated: AuditActionCustomerCreated,
+	EventCustomerUpdated: AuditActionCustomerUpdated,
+	EventCustomerDeleted: AuditActionCustomerDeleted,
+}
+
+// recordChange appends the audit entry and the outbox event of a change in the transaction of repos
+func recordChange(ctx context.Context, repos Repositories, eventType string, customerId uint, customer *Customer) error {
+	if err := repos.Audit.Append(ctx, newAuditEntry(ctx, eventAuditActions[eventType], customerId)); err != nil {
+		return err
+	}
+
+	message, err := newOutboxMessage(ctx, eventType, customerId, customer)
+	if err != nil {
+		return err
+	}
+	return repos.Outbox.Append(ctx, message)
+}
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -16,36 +20,106 @@ type mockCustomerRepo struct {
 	deleteFunc       func(customerId uint) error                                  // Port
 	searchFunc       func(customerId uint) error                                  // Port
 	validateNameFunc func(customerName string) error                              // Port
+	applyBatchFunc   func(operations []BatchOperation) ([]uint, error)            // Port
+	forEachBatchFunc func(batchSize int, fn func([]Customer) error) error         // Port
+	changesFunc      func(since uint, limit int) ([]ChangeRecord, error)          // Port
+	changeHeadFunc   func() (uint, error)                                         // Port
+	existingNameFunc func(names []string) ([]string, error)                       // Port
 }
 
-func (m *mockCustomerRepo) Save(customer Customer) error {
-	return m.saveFunc(customer)
+func (m *mockCustomerRepo) Save(ctx context.Context, customer *Customer) error {
+	return m.saveFunc(*customer)
 }
 
-func (m *mockCustomerRepo) Get(customerId uint) (*Customer, error) {
+func (m *mockCustomerRepo) Get(ctx context.Context, customerId uint) (*Customer, error) {
 	return m.getFunc(customerId)
 }
 
-func (m *mockCustomerRepo) GetAll() ([]Customer, error) {
+func (m *mockCustomerRepo) GetAll(ctx context.Context) ([]Customer, error) {
 	return m.getAllFunc()
 }
 
-func (m *mockCustomerRepo) Update(customerId uint, customer *Customer) (*Customer, error) {
+func (m *mockCustomerRepo) ForEachBatch(ctx context.Context, batchSize int, fn func(customers []Customer) error) error {
+	return m.forEachBatchFunc(batchSize, fn)
+}
+
+func (m *mockCustomerRepo) Update(ctx context.Context, customerId uint, customer *Customer) (*Customer, error) {
 	return m.updateFunc(customerId, customer)
 }
 
-func (m *mockCustomerRepo) Delete(customerId uint) error {
+func (m *mockCustomerRepo) Delete(ctx context.Context, customerId uint) error {
 	return m.deleteFunc(customerId)
 }
 
-func (m *mockCustomerRepo) Search(customerId uint) error {
+func (m *mockCustomerRepo) Search(ctx context.Context, customerId uint) error {
 	return m.searchFunc(customerId)
 }
 
+func (m *mockCustomerRepo) ApplyBatch(ctx context.Context, operations []BatchOperation) ([]uint, error) {
+	return m.applyBatchFunc(operations)
+}
+
+func (m *mockCustomerRepo) Changes(ctx context.Context, since uint, limit int) ([]ChangeRecord, error) {
+	return m.changesFunc(since, limit)
+}
+
+func (m *mockCustomerRepo) ChangeHead(ctx context.Context) (uint, error) {
+	return m.changeHeadFunc()
+}
+
+func (m *mockCustomerRepo) ExistingNames(ctx context.Context, names []string) ([]string, error) {
+	return m.existingNameFunc(names)
+}
+
 func (m *mockCustomerRepo) Validate(customerName string) error {
 	return m.validateNameFunc(customerName)
 }
 
+// Mock implementation of AuditRepository, it keeps the appended entries
+type mockAuditRepo struct {
+	entries []AuditEntry
+}
+
+func (m *mockAuditRepo) Append(ctx context.Context, entry *AuditEntry) error {
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+// Mock implementation of OutboxRepository, it keeps the appended messages
+type mockOutboxRepo struct {
+	messages []OutboxMessage
+}
+
+func (m *mockOutboxRepo) Append(ctx context.Context, message *OutboxMessage) error {
+	m.messages = append(m.messages, *message)
+	return nil
+}
+
+func (m *mockOutboxRepo) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error) {
+	return m.messages, nil
+}
+
+func (m *mockOutboxRepo) MarkPublished(ctx context.Context, messageId uint, publishedAt time.Time) error {
+	return nil
+}
+
+func (m *mockOutboxRepo) MarkFailed(ctx context.Context, messageId uint, nextAttemptAt time.Time, lastError string) error {
+	return nil
+}
+
+// mockUnitOfWork runs fn on the mock repositories without a transaction
+type mockUnitOfWork struct {
+	repos Repositories
+}
+
+func newMockUnitOfWork(repo CustomerRepository) *mockUnitOfWork {
+	return &mockUnitOfWork{repos: Repositories{Customers: repo, Audit: &mockAuditRepo{}, Outbox: &mockOutboxRepo{}}}
+}
+
+func (m *mockUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return fn(ctx, m.repos)
+}
+
 func TestCreateCustomer(t *testing.T) {
 	// Success case
 	t.Run("successful", func(t *testing.T) {
@@ -55,11 +129,35 @@ func TestCreateCustomer(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// Create a Customer in service and check Error
-		err := service.CreateCustomer(Customer{Name: "Fiat", Age: uint(24)})
+		err := service.CreateCustomer(context.Background(), Customer{Name: "Fiat", Age: uint(24)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("successful with audit entry and event", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			saveFunc: func(customer Customer) error {
+				// Simulate successful
+				return nil
+			},
+		}
+		uow := newMockUnitOfWork(repo)
+		service := NewCustomerService(repo, uow)
+		ctx := WithPrincipal(context.Background(), &Principal{Subject: "user-1"})
+
+		// Create a Customer in service and check the audit entry
+		err := service.CreateCustomer(ctx, Customer{Name: "Fiat", Age: uint(24)})
 		assert.NoError(t, err)
+		assert.Equal(t, []AuditEntry{{Actor: "user-1", Action: AuditActionCustomerCreated}}, uow.repos.Audit.(*mockAuditRepo).entries)
+
+		messages := uow.repos.Outbox.(*mockOutboxRepo).messages
+		assert.Len(t, messages, 1)
+		assert.Equal(t, EventCustomerCreated, messages[0].EventType)
+		var event CustomerEvent
+		assert.NoError(t, json.Unmarshal(messages[0].Payload, &event))
+		assert.Equal(t, "Fiat", event.Customer.Name)
 	})
 
 	// Failure case
@@ -70,10 +168,10 @@ func TestCreateCustomer(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// Create a Customer in service and check Error
-		err := service.CreateCustomer(Customer{Name: "Fiat", Age: uint(0)})
+		err := service.CreateCustomer(context.Background(), Customer{Name: "Fiat", Age: uint(0)})
 		assert.Error(t, err)
 		assert.Equal(t, "age must more than 0", err.Error())
 	})
@@ -85,10 +183,10 @@ func TestCreateCustomer(t *testing.T) {
 				return errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// Create a Customer in service and check Error
-		err := service.CreateCustomer(Customer{Name: "Fiat", Age: uint(24)})
+		err := service.CreateCustomer(context.Background(), Customer{Name: "Fiat", Age: uint(24)})
 		assert.Error(t, err)
 		assert.Equal(t, "database error", err.Error())
 	})
@@ -103,10 +201,10 @@ func TestGetCustomerById(t *testing.T) {
 				return &Customer{ID: uint(1), Name: "Fiat", Age: uint(24)}, nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// get a Customer from service by Id and check Value/Error
-		customer, err := service.GetCustomerById(uint(1))
+		customer, err := service.GetCustomerById(context.Background(), uint(1))
 		assert.Equal(t, uint(1), customer.ID)
 		assert.Equal(t, "Fiat", customer.Name)
 		assert.Equal(t, uint(24), customer.Age)
@@ -121,10 +219,10 @@ func TestGetCustomerById(t *testing.T) {
 				return &Customer{ID: uint(1), Name: "Fiat", Age: uint(24)}, nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// get a Customer from service by Id and check Value/Error
-		customer, err := service.GetCustomerById(uint(0))
+		customer, err := service.GetCustomerById(context.Background(), uint(0))
 		assert.Error(t, err)
 		assert.Equal(t, Customer{}, *customer)
 		assert.Equal(t, "customerId must more than 0", err.Error())
@@ -137,10 +235,10 @@ func TestGetCustomerById(t *testing.T) {
 				return &Customer{}, errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// get a Customer from service by Id and check Value/Error
-		customer, err := service.GetCustomerById(uint(1))
+		customer, err := service.GetCustomerById(context.Background(), uint(1))
 		assert.Error(t, err)
 		assert.Equal(t, Customer{}, *customer)
 		assert.Equal(t, "database error", err.Error())
@@ -159,7 +257,7 @@ func TestGetAllCustomer(t *testing.T) {
 				}, nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		expectedCustomers := []Customer{
 			{ID: uint(1), Name: "Fiat", Age: uint(24)},
@@ -167,7 +265,7 @@ func TestGetAllCustomer(t *testing.T) {
 		}
 
 		// get all Customers from service by Id and and check Value/Error
-		customers, err := service.GetAllCustomer()
+		customers, err := service.GetAllCustomer(context.Background())
 		assert.NoError(t, err)
 
 		// compare values
@@ -187,16 +285,70 @@ func TestGetAllCustomer(t *testing.T) {
 				return []Customer{}, errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// get all Customers from service by Id and check Value/Error
-		customers, err := service.GetAllCustomer()
+		customers, err := service.GetAllCustomer(context.Background())
 		assert.Error(t, err)
 		assert.Equal(t, []Customer{}, customers)
 		assert.Equal(t, "database error", err.Error())
 	})
 }
 
+func TestExportCustomers(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			forEachBatchFunc: func(batchSize int, fn func([]Customer) error) error {
+				// Simulate two batches
+				if err := fn([]Customer{{ID: uint(1), Name: "Fiat", Age: uint(24)}}); err != nil {
+					return err
+				}
+				return fn([]Customer{{ID: uint(2), Name: "Anfat", Age: uint(40)}})
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		// get the stream from service, run it and check Error
+		stream, err := service.ExportCustomers(context.Background())
+		assert.NoError(t, err)
+
+		var names []string
+		err = stream(func(customers []Customer) error {
+			for _, customer := range customers {
+				names = append(names, customer.Name)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Fiat", "Anfat"}, names)
+	})
+
+	// Failure case
+	t.Run("(fail) error of fn stops the stream", func(t *testing.T) {
+		batches := 0
+		repo := &mockCustomerRepo{
+			forEachBatchFunc: func(batchSize int, fn func([]Customer) error) error {
+				for i := 0; i < 3; i++ {
+					if err := fn([]Customer{{ID: uint(i + 1)}}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		stream, _ := service.ExportCustomers(context.Background())
+		err := stream(func(customers []Customer) error {
+			batches++
+			return errors.New("client gone")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, batches)
+	})
+}
+
 func TestUpdateCustomer(t *testing.T) {
 	// Success case
 	t.Run("successful", func(t *testing.T) {
@@ -206,10 +358,10 @@ func TestUpdateCustomer(t *testing.T) {
 				return &Customer{ID: uint(1), Name: "Fiat", Age: uint(24)}, nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// update a customer in service by Id with Customer and check Value/Error
-		customer, err := service.UpdateCustomer(uint(1), &Customer{Name: "Fiat", Age: uint(24)})
+		customer, err := service.UpdateCustomer(context.Background(), uint(1), &Customer{Name: "Fiat", Age: uint(24)})
 		assert.NoError(t, err)
 		assert.Equal(t, uint(1), customer.ID)
 		assert.Equal(t, "Fiat", customer.Name)
@@ -224,10 +376,10 @@ func TestUpdateCustomer(t *testing.T) {
 				return &Customer{ID: uint(1), Name: "Fiat", Age: uint(24)}, nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// update a customer in service by Id with Customer  and check Value/Error
-		updatedCustomer, err := service.UpdateCustomer(uint(1), &Customer{Name: "Anfat", Age: uint(0)})
+		updatedCustomer, err := service.UpdateCustomer(context.Background(), uint(1), &Customer{Name: "Anfat", Age: uint(0)})
 		assert.Error(t, err)
 		assert.NotEqual(t, "Anfat", updatedCustomer.Name)
 		assert.Equal(t, "age must more than 0", err.Error())
@@ -240,10 +392,10 @@ func TestUpdateCustomer(t *testing.T) {
 				return &Customer{}, errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// update a customer in service by Id with Customer and check Value/Error
-		updatedCustomer, err := service.UpdateCustomer(uint(1), &Customer{Name: "Anfat", Age: uint(40)})
+		updatedCustomer, err := service.UpdateCustomer(context.Background(), uint(1), &Customer{Name: "Anfat", Age: uint(40)})
 		assert.Error(t, err)
 		assert.Equal(t, &Customer{}, updatedCustomer)
 		assert.Equal(t, "database error", err.Error())
@@ -259,10 +411,10 @@ func TestDeleteCustomer(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// delete a customer in service by Id and check Error
-		err := service.DeleteCustomer(uint(1))
+		err := service.DeleteCustomer(context.Background(), uint(1))
 		assert.NoError(t, err)
 	})
 
@@ -274,10 +426,10 @@ func TestDeleteCustomer(t *testing.T) {
 				return errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// delete a customer in service by Id and check Error
-		err := service.DeleteCustomer(uint(1))
+		err := service.DeleteCustomer(context.Background(), uint(1))
 		assert.Error(t, err)
 		assert.Equal(t, "database error", err.Error())
 	})
@@ -292,9 +444,9 @@ func TestSearchCustomerById(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
-		err := service.SearchCustomerById(uint(1))
+		err := service.SearchCustomerById(context.Background(), uint(1))
 		assert.NoError(t, err)
 	})
 
@@ -306,10 +458,10 @@ func TestSearchCustomerById(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// search a customer in service by Id and check Error
-		err := service.SearchCustomerById(uint(0))
+		err := service.SearchCustomerById(context.Background(), uint(0))
 		assert.Error(t, err)
 		assert.Equal(t, "customerId must more than 0", err.Error())
 	})
@@ -321,10 +473,10 @@ func TestSearchCustomerById(t *testing.T) {
 				return errors.New("database error")
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// search a customer in service by Id and check Error
-		err := service.SearchCustomerById(uint(1))
+		err := service.SearchCustomerById(context.Background(), uint(1))
 		assert.Error(t, err)
 		assert.Equal(t, "database error", err.Error())
 	})
@@ -339,7 +491,7 @@ func TestValidateName(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// validate name and check Error
 		err := service.ValidateName("Anfat Nilaingan")
@@ -354,7 +506,7 @@ func TestValidateName(t *testing.T) {
 				return nil
 			},
 		}
-		service := NewCustomerService(repo)
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
 
 		// validate name and check Error
 		err := service.ValidateName("Anfat !@#$%")
@@ -362,3 +514,419 @@ func TestValidateName(t *testing.T) {
 		assert.Equal(t, "invalid name", err.Error())
 	})
 }
+
+func TestExecuteBatch(t *testing.T) {
+	operations := []BatchOperation{
+		{Operation: BatchOperationCreate, Customer: Customer{Name: "Fiat", Age: uint(24)}},
+		{Operation: BatchOperationUpdate, ID: uint(2), Customer: Customer{Name: "Anfat", Age: uint(30)}},
+		{Operation: BatchOperationDelete, ID: uint(3)},
+	}
+
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				// Simulate successful
+				return []uint{1, 2, 3}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		// execute the batch in service and check Error
+		results, err := service.ExecuteBatch(context.Background(), operations, true)
+		assert.NoError(t, err)
+		assert.Len(t, results, 3)
+		for index, result := range results {
+			assert.NoError(t, result.Err)
+			assert.Equal(t, uint(index+1), result.ID)
+		}
+	})
+
+	t.Run("successful best effort retries a failed chunk one by one", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				// Simulate failure of the update
+				for index, operation := range operations {
+					if operation.Operation == BatchOperationUpdate {
+						return nil, &BatchItemError{Index: index, Err: ErrNameAlreadyExists}
+					}
+				}
+				return []uint{operations[0].ID + 10}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		results, err := service.ExecuteBatch(context.Background(), operations, false)
+		assert.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, ErrNameAlreadyExists, results[1].Err)
+		assert.NoError(t, results[2].Err)
+		assert.Equal(t, uint(13), results[2].ID)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid operation aborts an atomic batch", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				t.Fatal("ApplyBatch must not be called")
+				return nil, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		invalid := append([]BatchOperation{{Operation: BatchOperationCreate, Customer: Customer{Name: "Fiat", Age: uint(0)}}}, operations[1:]...)
+		results, err := service.ExecuteBatch(context.Background(), invalid, true)
+		assert.NoError(t, err)
+		assert.Equal(t, ErrInvalidAge, results[0].Err)
+		assert.Equal(t, ErrBatchAborted, results[1].Err)
+		assert.Equal(t, ErrBatchAborted, results[2].Err)
+	})
+
+	t.Run("(fail) repository error aborts an atomic batch", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				// Simulate failure
+				return nil, &BatchItemError{Index: 2, Err: errors.New("database error")}
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		results, err := service.ExecuteBatch(context.Background(), operations, true)
+		assert.NoError(t, err)
+		assert.Equal(t, ErrBatchAborted, results[0].Err)
+		assert.Equal(t, "database error", results[2].Err.Error())
+	})
+
+	t.Run("(fail) empty and too large batch", func(t *testing.T) {
+		service := NewCustomerService(&mockCustomerRepo{}, newMockUnitOfWork(&mockCustomerRepo{}))
+
+		_, err := service.ExecuteBatch(context.Background(), []BatchOperation{}, false)
+		assert.Equal(t, ErrBatchEmpty, err)
+
+		_, err = service.ExecuteBatch(context.Background(), make([]BatchOperation, MaxBatchSize+1), false)
+		assert.Equal(t, ErrBatchTooLarge, err)
+	})
+}
+
+// sliceCustomerSource returns the given rows one by one
+type sliceCustomerSource struct {
+	rows []ImportRow
+}
+
+func (s *sliceCustomerSource) Next() (ImportRow, error) {
+	if len(s.rows) == 0 {
+		return ImportRow{}, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func TestImportCustomers(t *testing.T) {
+	rows := func() *sliceCustomerSource {
+		return &sliceCustomerSource{rows: []ImportRow{
+			{Line: 2, Customer: Customer{Name: "Fiat", Age: uint(24)}},
+			{Line: 3, Customer: Customer{Name: "Anfat", Age: uint(0)}},
+			{Line: 4, Err: errors.New("wrong number of fields")},
+			{Line: 5, Customer: Customer{Name: "Fiat", Age: uint(30)}},
+			{Line: 6, Customer: Customer{Name: "Somchai", Age: uint(30)}},
+		}}
+	}
+
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		var applied []BatchOperation
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				// Simulate successful
+				applied = append(applied, operations...)
+				return []uint{1, 2}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		// import the rows in service and check Error
+		report, err := service.ImportCustomers(context.Background(), rows(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, report.Rows)
+		assert.Equal(t, 2, report.Valid)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 3, report.Failed)
+		assert.Equal(t, []ImportRowError{
+			{Line: 3, Err: ErrInvalidAge},
+			{Line: 4, Err: errors.New("wrong number of fields")},
+			{Line: 5, Err: ErrDuplicateImportName},
+		}, report.Errors)
+		assert.Len(t, applied, 2)
+	})
+
+	t.Run("successful dry run", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				t.Fatal("ApplyBatch must not be called in a dry run")
+				return nil, nil
+			},
+			existingNameFunc: func(names []string) ([]string, error) {
+				assert.Equal(t, []string{"Fiat", "Somchai"}, names)
+				return []string{}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		report, err := service.ImportCustomers(context.Background(), rows(), true)
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Valid)
+		assert.Equal(t, 0, report.Imported)
+		assert.Equal(t, 3, report.Failed)
+	})
+
+	t.Run("successful dry run with names already stored", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			existingNameFunc: func(names []string) ([]string, error) {
+				// Simulate the name of Somchai already exists
+				return []string{"Somchai"}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		report, err := service.ImportCustomers(context.Background(), rows(), true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Valid)
+		assert.Equal(t, 4, report.Failed)
+		assert.Equal(t, ImportRowError{Line: 6, Err: ErrNameAlreadyExists}, report.Errors[3])
+	})
+
+	t.Run("successful with rows rejected by the repository", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			applyBatchFunc: func(operations []BatchOperation) ([]uint, error) {
+				// Simulate the name of Somchai already exists
+				for index, operation := range operations {
+					if operation.Customer.Name == "Somchai" {
+						return nil, &BatchItemError{Index: index, Err: ErrNameAlreadyExists}
+					}
+				}
+				return []uint{1}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		report, err := service.ImportCustomers(context.Background(), rows(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, ImportRowError{Line: 6, Err: ErrNameAlreadyExists}, report.Errors[3])
+	})
+
+	// Failure case
+	t.Run("(fail) canceled", func(t *testing.T) {
+		service := NewCustomerService(&mockCustomerRepo{}, newMockUnitOfWork(&mockCustomerRepo{}))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := service.ImportCustomers(ctx, rows(), false)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("(fail) dry run database error", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			existingNameFunc: func(names []string) ([]string, error) {
+				return nil, errors.New("database error")
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		_, err := service.ImportCustomers(context.Background(), rows(), true)
+		assert.Equal(t, "database error", err.Error())
+	})
+}
+
+func TestStreamCustomerEvents(t *testing.T) {
+	// changeLog is a change log of the creates of customers 1 to count, the sequence is the customer id
+	type changeLog struct {
+		changes []ChangeRecord
+	}
+	add := func(log *changeLog, operation string, customerId uint) {
+		log.changes = append(log.changes, ChangeRecord{Sequence: customerId, Operation: operation, CustomerID: customerId})
+	}
+	setupStream := func(count int) (*customerServiceImpl, *changeLog) {
+		log := &changeLog{}
+		for i := 1; i <= count; i++ {
+			add(log, ChangeCreate, uint(i))
+		}
+		repo := &mockCustomerRepo{
+			changesFunc: func(since uint, limit int) ([]ChangeRecord, error) {
+				var changes []ChangeRecord
+				for _, change := range log.changes {
+					if change.Sequence > since && len(changes) < limit {
+						changes = append(changes, change)
+					}
+				}
+				return changes, nil
+			},
+			changeHeadFunc: func() (uint, error) {
+				return uint(len(log.changes)), nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo)).(*customerServiceImpl)
+		service.pollInterval = time.Millisecond
+		return service, log
+	}
+	// collect reads the stream until count events arrived, the customer 4 is deleted while the stream waits
+	collect := func(t *testing.T, stream CustomerEventStream, log *changeLog, count int) []StreamedEvent {
+		var events []StreamedEvent
+		polls := 0
+		err := stream(func(batch []StreamedEvent) error {
+			polls++
+			events = append(events, batch...)
+			if polls == 2 {
+				add(log, ChangeDelete, uint(4))
+			}
+			if len(events) == count {
+				return errors.New("client gone")
+			}
+			return nil
+		})
+		assert.Equal(t, "client gone", err.Error())
+		return events
+	}
+
+	// Success case
+	t.Run("successful resumed after the last event id", func(t *testing.T) {
+		service, log := setupStream(3)
+		lastEventId := uint(1)
+
+		stream, err := service.StreamCustomerEvents(context.Background(), &lastEventId)
+		assert.NoError(t, err)
+
+		// collect the events until an event that is added while the stream waits arrives
+		events := collect(t, stream, log, 3)
+		var ids []uint
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			assert.Equal(t, event.ID, event.Event.CustomerID)
+		}
+		assert.Equal(t, []uint{2, 3, 4}, ids)
+		assert.Equal(t, EventCustomerCreated, events[0].Event.Type)
+		assert.Equal(t, EventCustomerDeleted, events[2].Event.Type)
+	})
+
+	t.Run("successful from the head without last event id", func(t *testing.T) {
+		service, log := setupStream(3)
+
+		stream, err := service.StreamCustomerEvents(context.Background(), nil)
+		assert.NoError(t, err)
+
+		// the events before the client connected are not sent
+		events := collect(t, stream, log, 1)
+		assert.Equal(t, uint(4), events[0].ID)
+	})
+
+	// Failure case
+	t.Run("(fail) canceled context ends the stream", func(t *testing.T) {
+		service, _ := setupStream(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		lastEventId := uint(0)
+
+		stream, _ := service.StreamCustomerEvents(ctx, &lastEventId)
+		err := stream(func(events []StreamedEvent) error {
+			cancel()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("(fail) database error on the head", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			changeHeadFunc: func() (uint, error) {
+				return 0, errors.New("database error")
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		_, err := service.StreamCustomerEvents(context.Background(), nil)
+		assert.Equal(t, "database error", err.Error())
+	})
+}
+
+func TestGetChanges(t *testing.T) {
+	// Success case
+	t.Run("successful with the default limit", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			changesFunc: func(since uint, limit int) ([]ChangeRecord, error) {
+				assert.Equal(t, uint(7), since)
+				assert.Equal(t, DefaultChangeLimit, limit)
+				return []ChangeRecord{{Sequence: 8, Operation: ChangeDelete, CustomerID: 1, Deleted: true}}, nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		changes, err := service.GetChanges(context.Background(), uint(7), 0)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 1)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid limit", func(t *testing.T) {
+		repo := &mockCustomerRepo{}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		for _, limit := range []int{-1, MaxChangeLimit + 1} {
+			changes, err := service.GetChanges(context.Background(), uint(0), limit)
+			assert.Equal(t, ErrInvalidChangeLimit, err)
+			assert.Equal(t, []ChangeRecord{}, changes)
+		}
+	})
+
+	t.Run("(fail) database error", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			changesFunc: func(since uint, limit int) ([]ChangeRecord, error) {
+				return nil, errors.New("database error")
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		changes, err := service.GetChanges(context.Background(), uint(0), 10)
+		assert.Equal(t, "database error", err.Error())
+		assert.Equal(t, []ChangeRecord{}, changes)
+	})
+}
+
+func TestSeedCustomers(t *testing.T) {
+	customers := []Customer{{Name: "Fiat", Age: uint(24)}, {Name: "Anfat", Age: uint(40)}}
+
+	// Success case
+	t.Run("successful seed skips existing names", func(t *testing.T) {
+		var saved []string
+		repo := &mockCustomerRepo{
+			saveFunc: func(customer Customer) error {
+				// Simulate Fiat was seeded before
+				if customer.Name == "Fiat" {
+					return ErrNameAlreadyExists
+				}
+				saved = append(saved, customer.Name)
+				return nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		report, err := SeedCustomers(context.Background(), service, customers)
+		assert.NoError(t, err)
+		assert.Equal(t, &SeedReport{Created: 1, Existing: 1}, report)
+		assert.Equal(t, []string{"Anfat"}, saved)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid customer stops the seed", func(t *testing.T) {
+		repo := &mockCustomerRepo{
+			saveFunc: func(customer Customer) error {
+				return nil
+			},
+		}
+		service := NewCustomerService(repo, newMockUnitOfWork(repo))
+
+		report, err := SeedCustomers(context.Background(), service, []Customer{{Name: "Fiat", Age: uint(24)}, {Name: "Anfat"}, {Name: "Bank", Age: uint(30)}})
+		assert.ErrorIs(t, err, ErrInvalidAge)
+		assert.Equal(t, `customer "Anfat": age must more than 0`, err.Error())
+		assert.Equal(t, &SeedReport{Created: 1}, report)
+	})
+}
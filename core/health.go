@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ! Primary Port (health.go)
+type HealthService interface {
+	Live(ctx context.Context) HealthReport
+	Ready(ctx context.Context) HealthReport
+}
+
+//* Secondary Port (health.go)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error // Port
+}
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+
+	// DefaultHealthCheckTimeout bounds each check, a hanging dependency is reported as down
+	DefaultHealthCheckTimeout = 2 * time.Second
+)
+
+// HealthCheck is one dependency the service needs to serve requests
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// NewDatabaseCheck checks the connection to the database through the HealthRepository port
+func NewDatabaseCheck(r HealthRepository) HealthCheck {
+	return HealthCheck{Name: "database", Check: r.Ping}
+}
+
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is up only when every check is up
+type HealthReport struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks,omitempty"`
+}
+
+func (r HealthReport) Up() bool {
+	return r.Status == HealthStatusUp
+}
+
+// Implement HealthService
+type healthServiceImpl struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func NewHealthService(timeout time.Duration, checks ...HealthCheck) HealthService {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &healthServiceImpl{checks: checks, timeout: timeout}
+}
+
+// Live only reports that the process answers, a failing dependency must not get a live process restarted
+func (s *healthServiceImpl) Live(ctx context.Context) HealthReport {
+	return HealthReport{Status: HealthStatusUp}
+}
+
+// Ready runs every check at once and reports each with its latency, in the order the checks were given
+func (s *healthServiceImpl) Ready(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthStatusUp, Checks: make([]HealthCheckResult, len(s.checks))}
+
+	var wg sync.WaitGroup
+	for index, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[index] = s.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != HealthStatusUp {
+			report.Status = HealthStatusDown
+		}
+	}
+	return report
+}
+
+func (s *healthServiceImpl) run(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// call Check() and check Error, a check ignoring ctx still gets reported once the timeout passed
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := HealthCheckResult{
+		Name:      check.Name,
+		Status:    HealthStatusUp,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
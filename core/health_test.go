@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pingFunc is a HealthRepository answering with its func
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestHealthService(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+
+	// Success case
+	t.Run("successful ready", func(t *testing.T) {
+		service := NewHealthService(time.Second, NewDatabaseCheck(pingFunc(up)), HealthCheck{Name: "workers", Check: up})
+
+		// call Ready() and check every check is up in order
+		report := service.Ready(context.Background())
+		assert.True(t, report.Up())
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, "workers", report.Checks[1].Name)
+		assert.Equal(t, HealthStatusUp, report.Checks[1].Status)
+		assert.Empty(t, report.Checks[1].Error)
+	})
+
+	t.Run("successful live without checks", func(t *testing.T) {
+		down := func(ctx context.Context) error { return errors.New("down") }
+		service := NewHealthService(time.Second, HealthCheck{Name: "database", Check: down})
+
+		report := service.Live(context.Background())
+		assert.True(t, report.Up())
+		assert.Empty(t, report.Checks)
+	})
+
+	// Failure case
+	t.Run("(fail) failing check", func(t *testing.T) {
+		ping := pingFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+		service := NewHealthService(time.Second, NewDatabaseCheck(ping), HealthCheck{Name: "workers", Check: up})
+
+		report := service.Ready(context.Background())
+		assert.False(t, report.Up())
+		assert.Equal(t, HealthStatusDown, report.Checks[0].Status)
+		assert.Equal(t, "connection refused", report.Checks[0].Error)
+		assert.Equal(t, HealthStatusUp, report.Checks[1].Status)
+	})
+
+	t.Run("(fail) hanging check times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		hanging := func(ctx context.Context) error { <-release; return nil }
+		service := NewHealthService(20*time.Millisecond, HealthCheck{Name: "migrations", Check: hanging})
+
+		report := service.Ready(context.Background())
+		assert.False(t, report.Up())
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+		assert.GreaterOrEqual(t, report.Checks[0].LatencyMs, 20.0)
+	})
+}
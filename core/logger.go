@@ -0,0 +1,22 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerContextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger, the adapters put the logger of a request (with its request id)
+// into the context so the logs of core can be correlated with the request
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger of ctx, or the default logger of slog when there is none
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
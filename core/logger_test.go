@@ -0,0 +1,35 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	t.Run("default logger without logger", func(t *testing.T) {
+		assert.Equal(t, slog.Default(), LoggerFromContext(context.Background()))
+	})
+
+	t.Run("logger of the request", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&out, nil)).With("request_id", "req-1")
+		ctx := WithLogger(context.Background(), logger)
+
+		LoggerFromContext(ctx).Info("hello")
+		assert.Contains(t, out.String(), "request_id=req-1")
+	})
+
+	t.Run("denied permission is logged with the principal", func(t *testing.T) {
+		var out bytes.Buffer
+		ctx := WithLogger(context.Background(), slog.New(slog.NewTextHandler(&out, nil)))
+		ctx = WithPrincipal(ctx, &Principal{Subject: "user-1", TenantID: "bank-a"})
+
+		err := NewPolicy(nil).Authorize(ctx, PermissionCustomersWrite)
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.Contains(t, out.String(), `msg="permission denied" subject=user-1 tenant=bank-a permission=customers:write`)
+	})
+}
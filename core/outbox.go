@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Types of the customer events
+const (
+	EventCustomerCreated = "CustomerCreated"
+	EventCustomerUpdated = "CustomerUpdated"
+	EventCustomerDeleted = "CustomerDeleted"
+)
+
+// CustomerEvent tells downstream systems that a customer changed, Customer is the state after the change
+// and is empty for CustomerDeleted
+type CustomerEvent struct {
+	Type       string    `json:"type"`
+	TenantID   string    `json:"tenant_id"`
+	CustomerID uint      `json:"customer_id"`
+	Customer   *Customer `json:"customer,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// OutboxMessage is an event written in the same transaction as the change it describes, it is kept until the
+// relay published it. The ID identifies the event, so consumers can drop the duplicates of at-least-once delivery
+type OutboxMessage struct {
+	ID            uint      `gorm:"primaryKey"`
+	TenantID      string    `gorm:"not null;default:'';index"`
+	EventType     string    `gorm:"not null"`
+	CustomerID    uint      `gorm:"index"`
+	Payload       []byte    `gorm:"not null"`
+	OccurredAt    time.Time `gorm:"not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	PublishedAt   *time.Time `gorm:"index"`
+}
+
+//* Secondary Port (outbox.go)
+
+type OutboxRepository interface {
+	// Append writes the message into the tenant of ctx
+	Append(ctx context.Context, message *OutboxMessage) error // Port
+	// ClaimPending returns up to limit unpublished messages of every tenant that are due at now, oldest first,
+	// and hides them from other claims for lease, so relays of several instances rarely publish the same message
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error) // Port
+	MarkPublished(ctx context.Context, messageId uint, publishedAt time.Time) error                           // Port
+	// MarkFailed counts the failed attempt and sets when the message is due again
+	MarkFailed(ctx context.Context, messageId uint, nextAttemptAt time.Time, lastError string) error // Port
+}
+
+// Publisher sends an outbox message to downstream systems, a returned error makes the relay retry it later
+type Publisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error // Port
+}
+
+// newOutboxMessage records the event of a change of the customer, customer is nil for a delete
+func newOutboxMessage(ctx context.Context, eventType string, customerId uint, customer *Customer) (*OutboxMessage, error) {
+	now := time.Now().UTC()
+	event := CustomerEvent{
+		Type:       eventType,
+		TenantID:   TenantFromContext(ctx),
+		CustomerID: customerId,
+		Customer:   customer,
+		OccurredAt: now,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessage{
+		EventType:     eventType,
+		CustomerID:    customerId,
+		Payload:       payload,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// MultiPublisher publishes every message through all of its publishers in order, the first error stops it, so the
+// relay retries the message for all of them and the ones that already succeeded see it again
+type MultiPublisher []Publisher
+
+func (p MultiPublisher) Publish(ctx context.Context, message OutboxMessage) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
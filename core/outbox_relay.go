@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxRelayConfig configures the relay, zero values are replaced by the defaults of NewOutboxRelay
+type OutboxRelayConfig struct {
+	// BatchSize is the number of messages claimed at once
+	BatchSize int
+	// PollInterval is the wait between two polls when the outbox is drained
+	PollInterval time.Duration
+	// Lease is how long claimed messages are hidden from other relays
+	Lease time.Duration
+	// MinBackoff is the wait after the first failed attempt, it doubles with every attempt up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// OutboxRelay publishes the outbox messages through the Publisher. A message is marked as published only after
+// Publish returned nil, so a crash in between publishes it again: delivery is at-least-once
+type OutboxRelay struct {
+	outbox    OutboxRepository
+	publisher Publisher
+	config    OutboxRelayConfig
+	now       func() time.Time
+}
+
+func NewOutboxRelay(outbox OutboxRepository, publisher Publisher, config OutboxRelayConfig) *OutboxRelay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = time.Minute
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 10 * time.Minute
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+
+	// times are in UTC like the ones of newOutboxMessage, so databases comparing them as text agree
+	now := func() time.Time { return time.Now().UTC() }
+	return &OutboxRelay{outbox: outbox, publisher: publisher, config: config, now: now}
+}
+
+// Run relays the messages until ctx is canceled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	for {
+		// poll again right away while full batches are claimed, the outbox is not drained yet
+		published, err := r.RelayOnce(ctx)
+		if err == nil && published == r.config.BatchSize {
+			continue
+		}
+		if err != nil && ctx.Err() == nil {
+			LoggerFromContext(ctx).ErrorContext(ctx, "outbox relay failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due messages and returns how many were claimed
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	// call ClaimPending() to get the due messages from gorm adapter and check Error
+	messages, err := r.outbox.ClaimPending(ctx, r.now(), r.config.Lease, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		if err := ctx.Err(); err != nil {
+			// the lease of the remaining messages runs out, so they are claimed again
+			return len(messages), err
+		}
+
+		// publish the message, a failure is retried after the backoff of its attempts
+		if err := r.publisher.Publish(ctx, message); err != nil {
+			nextAttemptAt := r.now().Add(exponentialBackoff(r.config.MinBackoff, r.config.MaxBackoff, message.Attempts+1))
+			if err := r.outbox.MarkFailed(ctx, message.ID, nextAttemptAt, err.Error()); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+
+		if err := r.outbox.MarkPublished(ctx, message.ID, r.now()); err != nil {
+			return len(messages), err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// exponentialBackoff is the wait before the next attempt after the given number of failed attempts, it starts at
+// minWait and doubles with every attempt up to maxWait
+func exponentialBackoff(minWait time.Duration, maxWait time.Duration, attempts int) time.Duration {
+	wait := minWait
+	for i := 1; i < attempts && wait < maxWait; i++ {
+		wait *= 2
+	}
+	return min(wait, maxWait)
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// relayOutboxRepo is a mock OutboxRepository that records the outcome of every message
+type relayOutboxRepo struct {
+	pending   []OutboxMessage
+	claimErr  error
+	published []uint
+	failed    map[uint]time.Time
+}
+
+func (m *relayOutboxRepo) Append(ctx context.Context, message *OutboxMessage) error {
+	return nil
+}
+
+func (m *relayOutboxRepo) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxMessage, error) {
+	return m.pending, m.claimErr
+}
+
+func (m *relayOutboxRepo) MarkPublished(ctx context.Context, messageId uint, publishedAt time.Time) error {
+	m.published = append(m.published, messageId)
+	return nil
+}
+
+func (m *relayOutboxRepo) MarkFailed(ctx context.Context, messageId uint, nextAttemptAt time.Time, lastError string) error {
+	m.failed[messageId] = nextAttemptAt
+	return nil
+}
+
+// publisherFunc adapts a function to Publisher
+type publisherFunc func(message OutboxMessage) error
+
+func (f publisherFunc) Publish(ctx context.Context, message OutboxMessage) error {
+	return f(message)
+}
+
+func TestOutboxRelay(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setupRelay := func(repo *relayOutboxRepo, publish publisherFunc) *OutboxRelay {
+		relay := NewOutboxRelay(repo, publish, OutboxRelayConfig{MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+		relay.now = func() time.Time { return start }
+		return relay
+	}
+
+	// Success case
+	t.Run("successful publish", func(t *testing.T) {
+		repo := &relayOutboxRepo{pending: []OutboxMessage{{ID: 1}, {ID: 2}}, failed: map[uint]time.Time{}}
+		relay := setupRelay(repo, func(message OutboxMessage) error {
+			return nil
+		})
+
+		// relay a batch and check Error
+		published, err := relay.RelayOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		assert.Equal(t, []uint{1, 2}, repo.published)
+	})
+
+	// Failure case
+	t.Run("(fail) publish error is retried with backoff", func(t *testing.T) {
+		repo := &relayOutboxRepo{
+			pending: []OutboxMessage{{ID: 1}, {ID: 2, Attempts: 2}, {ID: 3, Attempts: 10}, {ID: 4}},
+			failed:  map[uint]time.Time{},
+		}
+		relay := setupRelay(repo, func(message OutboxMessage) error {
+			if message.ID == 4 {
+				return nil
+			}
+			return errors.New("broker unavailable")
+		})
+
+		_, err := relay.RelayOnce(context.Background())
+		assert.NoError(t, err)
+		// the other messages are still published
+		assert.Equal(t, []uint{4}, repo.published)
+		assert.Equal(t, start.Add(time.Second), repo.failed[1])
+		assert.Equal(t, start.Add(4*time.Second), repo.failed[2])
+		assert.Equal(t, start.Add(5*time.Second), repo.failed[3])
+	})
+
+	t.Run("(fail) claim error", func(t *testing.T) {
+		repo := &relayOutboxRepo{claimErr: errors.New("database error")}
+		relay := setupRelay(repo, func(message OutboxMessage) error {
+			return nil
+		})
+
+		_, err := relay.RelayOnce(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("(fail) canceled", func(t *testing.T) {
+		repo := &relayOutboxRepo{pending: []OutboxMessage{{ID: 1}}, failed: map[uint]time.Time{}}
+		relay := setupRelay(repo, func(message OutboxMessage) error {
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := relay.RelayOnce(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, repo.published)
+
+		// Run returns once ctx is canceled
+		relay.Run(ctx)
+	})
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Permissions checked per CustomerService operation
+const (
+	PermissionCustomersRead   = "customers:read"
+	PermissionCustomersWrite  = "customers:write"
+	PermissionCustomersDelete = "customers:delete"
+	PermissionPIIRead         = "pii:read"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Policy grants permissions to principals, either directly by their scopes or through their roles
+type Policy struct {
+	roleScopes map[string][]string
+}
+
+func NewPolicy(roleScopes map[string][]string) *Policy {
+	return &Policy{roleScopes: roleScopes}
+}
+
+// Allows reports whether the principal holds the permission
+func (p *Policy) Allows(principal *Principal, permission string) bool {
+	if slices.Contains(principal.Scopes, permission) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if slices.Contains(p.roleScopes[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the principal of ctx, it returns ErrUnauthenticated for anonymous calls and ErrForbidden
+// (wrapped with the missing permission) when the permission is not granted
+func (p *Policy) Authorize(ctx context.Context, permission string) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !p.Allows(principal, permission) {
+		LoggerFromContext(ctx).WarnContext(ctx, "permission denied",
+			"subject", principal.Subject, "tenant", principal.TenantID, "permission", permission)
+		return fmt.Errorf("%w: missing permission %s", ErrForbidden, permission)
+	}
+	return nil
+}
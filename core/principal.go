@@ -0,0 +1,31 @@
+package core
+
+import "context"
+
+// Principal is the authenticated caller of a request, it is put into the context by the
+// authentication adapters so core can read who is acting (e.g. for auditing)
+type Principal struct {
+	Subject    string
+	TenantID   string
+	Roles      []string
+	Scopes     []string
+	AuthMethod string
+}
+
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodApiKey = "api_key"
+)
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal of ctx, ok is false for anonymous calls
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,25 @@
+package core
+
+import "context"
+
+// DefaultTenantID is used by callers that belong to no member bank (e.g. single-tenant deployments)
+const DefaultTenantID = ""
+
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the tenant, it is meant for callers without a principal
+// (e.g. CLI commands and background jobs), the tenant of an authenticated principal always wins
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantId)
+}
+
+// TenantFromContext returns the tenant every repository query of ctx is scoped to
+func TenantFromContext(ctx context.Context) string {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal.TenantID
+	}
+	if tenantId, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenantId
+	}
+	return DefaultTenantID
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantFromContext(t *testing.T) {
+	t.Run("default tenant without principal", func(t *testing.T) {
+		assert.Equal(t, DefaultTenantID, TenantFromContext(context.Background()))
+	})
+
+	t.Run("tenant of the principal", func(t *testing.T) {
+		ctx := WithPrincipal(context.Background(), &Principal{Subject: "user-1", TenantID: "bank-a"})
+		assert.Equal(t, "bank-a", TenantFromContext(ctx))
+	})
+
+	t.Run("explicit tenant for callers without principal", func(t *testing.T) {
+		ctx := WithTenant(context.Background(), "bank-b")
+		assert.Equal(t, "bank-b", TenantFromContext(ctx))
+	})
+
+	t.Run("principal wins over an explicit tenant", func(t *testing.T) {
+		ctx := WithTenant(context.Background(), "bank-b")
+		ctx = WithPrincipal(ctx, &Principal{Subject: "user-1", TenantID: "bank-a"})
+		assert.Equal(t, "bank-a", TenantFromContext(ctx))
+	})
+}
@@ -0,0 +1,21 @@
+package core
+
+import "context"
+
+//* Secondary Port (unit_of_work.go)
+
+// Repositories are handed to the callback of a UnitOfWork, every one of them runs in the same transaction
+type Repositories struct {
+	Customers CustomerRepository
+	Audit     AuditRepository
+	Outbox    OutboxRepository
+}
+
+// UnitOfWork runs fn in a transaction, it is committed when fn returns nil and rolled back when fn returns an
+// error or panics, the panic is passed on after the rollback.
+//
+// Do called again with the ctx given to fn joins the running transaction in a savepoint: an error of the nested
+// call only rolls back its own changes, unless the outer fn returns it too, which rolls back everything
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
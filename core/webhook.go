@@ -0,0 +1,240 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WebhookSubscription sends the events of EventTypes of its tenant to URL, the bodies are signed with Secret
+type WebhookSubscription struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TenantID   string    `gorm:"not null;default:'';index" json:"tenant_id"`
+	URL        string    `gorm:"not null" json:"url"`
+	EventTypes []string  `gorm:"serializer:json" json:"event_types"`
+	Secret     string    `gorm:"not null" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the subscription wants events of eventType
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	return slices.Contains(s.EventTypes, eventType)
+}
+
+// WebhookDelivery is one event to send to one subscription, it is retried until it is delivered or dead
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	TenantID       string     `gorm:"not null;default:'';index" json:"tenant_id"`
+	SubscriptionID uint       `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_message" json:"subscription_id"`
+	MessageID      uint       `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_message" json:"message_id"`
+	EventType      string     `gorm:"not null" json:"event_type"`
+	Payload        []byte     `gorm:"not null" json:"-"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	DeadAt         *time.Time `gorm:"index" json:"dead_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+var (
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEventType = errors.New("event types must be CustomerCreated, CustomerUpdated or CustomerDeleted")
+	ErrWebhookDeliveryNotDead  = errors.New("only dead deliveries can be redelivered")
+	ErrWebhookAddressForbidden = errors.New("webhook url must not point to a loopback, link-local, private or unspecified address")
+)
+
+// webhookEventTypes are the events a subscription can ask for
+var webhookEventTypes = []string{EventCustomerCreated, EventCustomerUpdated, EventCustomerDeleted}
+
+//* Secondary Port (webhook.go)
+
+// Subscriptions and deliveries are scoped to the tenant of ctx, except ClaimPending that serves every tenant
+type WebhookRepository interface {
+	SaveSubscription(ctx context.Context, subscription *WebhookSubscription) error          // Port
+	GetAllSubscriptions(ctx context.Context) ([]WebhookSubscription, error)                 // Port
+	DeleteSubscription(ctx context.Context, subscriptionId uint) error                      // Port
+	GetSubscription(ctx context.Context, subscriptionId uint) (*WebhookSubscription, error) // Port
+	// SaveDeliveries inserts the deliveries, a delivery of the same subscription and message is only kept once
+	SaveDeliveries(ctx context.Context, deliveries []WebhookDelivery) error // Port
+	// ClaimPending returns up to limit deliveries of every tenant that are due at now and hides them for lease
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) // Port
+	GetDelivery(ctx context.Context, deliveryId uint) (*WebhookDelivery, error)                                 // Port
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error                                        // Port
+	GetDeadDeliveries(ctx context.Context) ([]WebhookDelivery, error)                                           // Port
+}
+
+// WebhookSender posts a delivery to the url of the subscription, an error or a status other than 2xx is a failure
+type WebhookSender interface {
+	Send(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) (statusCode int, err error) // Port
+}
+
+// ! Primary Port (webhook.go)
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, url string, eventTypes []string) (*WebhookSubscription, string, error)
+	GetAllSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionId uint) error
+	GetDeadLetters(ctx context.Context) ([]WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryId uint) error
+}
+
+// CheckWebhookAddress rejects the addresses of our own host and network, so a subscription can not make the
+// server call internal services. The sender checks every address it connects to again, as a host name may
+// resolve to another address later
+func CheckWebhookAddress(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsPrivate() || addr.IsUnspecified() {
+		return ErrWebhookAddressForbidden
+	}
+	return nil
+}
+
+// checkWebhookHost rejects host names of our own host and addresses of CheckWebhookAddress, other host names are
+// checked by the sender once they are resolved
+func checkWebhookHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookAddressForbidden
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return CheckWebhookAddress(addr)
+	}
+	return nil
+}
+
+// Implement WebhookRepository
+type webhookServiceImpl struct {
+	r   WebhookRepository
+	now func() time.Time
+}
+
+func NewWebhookService(repo WebhookRepository) WebhookService {
+	return &webhookServiceImpl{r: repo, now: func() time.Time { return time.Now().UTC() }}
+}
+
+func (s *webhookServiceImpl) CreateSubscription(ctx context.Context, rawURL string, eventTypes []string) (*WebhookSubscription, string, error) {
+	// Business logic...
+	// Check url and event types
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return &WebhookSubscription{}, "", ErrInvalidWebhookURL
+	}
+	if err := checkWebhookHost(parsed.Hostname()); err != nil {
+		return &WebhookSubscription{}, "", err
+	}
+	if len(eventTypes) == 0 {
+		return &WebhookSubscription{}, "", ErrInvalidWebhookEventType
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return &WebhookSubscription{}, "", ErrInvalidWebhookEventType
+		}
+	}
+
+	// the secret is shown once, the receiver uses it to verify the signature of every delivery
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return &WebhookSubscription{}, "", err
+	}
+	subscription := &WebhookSubscription{URL: rawURL, EventTypes: eventTypes, Secret: "whsec_" + hex.EncodeToString(secret), CreatedAt: s.now()}
+
+	// call SaveSubscription() to insert the subscription in gorm adapter
+	if err := s.r.SaveSubscription(ctx, subscription); err != nil {
+		return &WebhookSubscription{}, "", err
+	}
+
+	return subscription, subscription.Secret, nil
+}
+
+func (s *webhookServiceImpl) GetAllSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	// call GetAllSubscriptions() for get all subscriptions from gorm adapter
+	subscriptions, err := s.r.GetAllSubscriptions(ctx)
+	if err != nil {
+		return []WebhookSubscription{}, err
+	}
+
+	return subscriptions, nil
+}
+
+func (s *webhookServiceImpl) DeleteSubscription(ctx context.Context, subscriptionId uint) error {
+	// call DeleteSubscription() to delete a subscription in gorm adapter
+	return s.r.DeleteSubscription(ctx, subscriptionId)
+}
+
+func (s *webhookServiceImpl) GetDeadLetters(ctx context.Context) ([]WebhookDelivery, error) {
+	// call GetDeadDeliveries() for get the deliveries that ran out of attempts from gorm adapter
+	deliveries, err := s.r.GetDeadDeliveries(ctx)
+	if err != nil {
+		return []WebhookDelivery{}, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *webhookServiceImpl) Redeliver(ctx context.Context, deliveryId uint) error {
+	// call GetDelivery() to find the delivery and check Error
+	delivery, err := s.r.GetDelivery(ctx, deliveryId)
+	if err != nil {
+		return err
+	}
+	if delivery.DeadAt == nil {
+		return ErrWebhookDeliveryNotDead
+	}
+
+	// the delivery gets a fresh set of attempts, starting right away
+	delivery.DeadAt = nil
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = s.now()
+
+	return s.r.UpdateDelivery(ctx, delivery)
+}
+
+// WebhookPublisher is the Publisher of the outbox relay for webhooks, it turns a message into one delivery per
+// subscription of its tenant and event type. The WebhookDispatcher sends them, so a slow partner never holds up
+// the relay
+type WebhookPublisher struct {
+	r WebhookRepository
+}
+
+func NewWebhookPublisher(repo WebhookRepository) *WebhookPublisher {
+	return &WebhookPublisher{r: repo}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, message OutboxMessage) error {
+	ctx = WithTenant(ctx, message.TenantID)
+
+	// call GetAllSubscriptions() for get the subscriptions of the tenant of the message and check Error
+	subscriptions, err := p.r.GetAllSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(message.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			MessageID:      message.ID,
+			EventType:      message.EventType,
+			Payload:        message.Payload,
+			OccurredAt:     message.OccurredAt,
+			NextAttemptAt:  message.OccurredAt,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// call SaveDeliveries() to insert the deliveries, a message relayed again does not add them twice
+	return p.r.SaveDeliveries(ctx, deliveries)
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// WebhookDispatcherConfig configures the dispatcher, zero values are replaced by the defaults of NewWebhookDispatcher
+type WebhookDispatcherConfig struct {
+	// BatchSize is the number of deliveries claimed at once
+	BatchSize int
+	// PollInterval is the wait between two polls when no delivery is due
+	PollInterval time.Duration
+	// Lease is how long claimed deliveries are hidden from other dispatchers
+	Lease time.Duration
+	// MinBackoff is the wait after the first failed attempt, it doubles with every attempt up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of failed attempts after which a delivery is dead, it is kept as dead letter
+	MaxAttempts int
+}
+
+// WebhookDispatcher sends the due webhook deliveries and retries the failed ones with exponential backoff
+type WebhookDispatcher struct {
+	r      WebhookRepository
+	sender WebhookSender
+	config WebhookDispatcherConfig
+	now    func() time.Time
+}
+
+func NewWebhookDispatcher(repo WebhookRepository, sender WebhookSender, config WebhookDispatcherConfig) *WebhookDispatcher {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = 5 * time.Minute
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+
+	now := func() time.Time { return time.Now().UTC() }
+	return &WebhookDispatcher{r: repo, sender: sender, config: config, now: now}
+}
+
+// Run dispatches the deliveries until ctx is canceled
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	for {
+		// poll again right away while full batches are claimed
+		dispatched, err := d.DispatchOnce(ctx)
+		if err == nil && dispatched == d.config.BatchSize {
+			continue
+		}
+		if err != nil && ctx.Err() == nil {
+			LoggerFromContext(ctx).ErrorContext(ctx, "webhook dispatcher failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.config.PollInterval):
+		}
+	}
+}
+
+// DispatchOnce sends one batch of due deliveries and returns how many were claimed
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	// call ClaimPending() to get the due deliveries from gorm adapter and check Error
+	deliveries, err := d.r.ClaimPending(ctx, d.now(), d.config.Lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for index := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return len(deliveries), err
+		}
+		if err := d.dispatch(ctx, &deliveries[index]); err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// dispatch sends the delivery once and stores the outcome, only errors of the repository are returned
+func (d *WebhookDispatcher) dispatch(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx = WithTenant(ctx, delivery.TenantID)
+
+	// call GetSubscription() and Send() to post the delivery, a missing subscription counts as failed attempt
+	subscription, err := d.r.GetSubscription(ctx, delivery.SubscriptionID)
+	statusCode := 0
+	if err == nil {
+		statusCode, err = d.sender.Send(ctx, subscription, delivery)
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	switch {
+	case err == nil:
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.DeadAt = &now
+		delivery.LastError = err.Error()
+		LoggerFromContext(ctx).WarnContext(ctx, "webhook delivery dead-lettered", "delivery_id", delivery.ID,
+			"subscription_id", delivery.SubscriptionID, "attempts", delivery.Attempts, "error", err)
+	default:
+		delivery.NextAttemptAt = now.Add(exponentialBackoff(d.config.MinBackoff, d.config.MaxBackoff, delivery.Attempts))
+		delivery.LastError = err.Error()
+	}
+
+	// call UpdateDelivery() to store the outcome in gorm adapter
+	return d.r.UpdateDelivery(ctx, delivery)
+}
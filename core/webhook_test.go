@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockWebhookRepo keeps subscriptions and deliveries in memory
+type mockWebhookRepo struct {
+	subscriptions []WebhookSubscription
+	deliveries    []WebhookDelivery
+	updated       []WebhookDelivery
+}
+
+func (m *mockWebhookRepo) SaveSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	subscription.ID = uint(len(m.subscriptions) + 1)
+	m.subscriptions = append(m.subscriptions, *subscription)
+	return nil
+}
+
+func (m *mockWebhookRepo) GetAllSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	return m.subscriptions, nil
+}
+
+func (m *mockWebhookRepo) DeleteSubscription(ctx context.Context, subscriptionId uint) error {
+	return nil
+}
+
+func (m *mockWebhookRepo) GetSubscription(ctx context.Context, subscriptionId uint) (*WebhookSubscription, error) {
+	return &m.subscriptions[subscriptionId-1], nil
+}
+
+func (m *mockWebhookRepo) SaveDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	m.deliveries = append(m.deliveries, deliveries...)
+	return nil
+}
+
+func (m *mockWebhookRepo) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	return m.deliveries, nil
+}
+
+func (m *mockWebhookRepo) GetDelivery(ctx context.Context, deliveryId uint) (*WebhookDelivery, error) {
+	return &m.deliveries[deliveryId-1], nil
+}
+
+func (m *mockWebhookRepo) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	m.updated = append(m.updated, *delivery)
+	return nil
+}
+
+func (m *mockWebhookRepo) GetDeadDeliveries(ctx context.Context) ([]WebhookDelivery, error) {
+	return m.deliveries, nil
+}
+
+// senderFunc adapts a function to WebhookSender
+type senderFunc func(delivery *WebhookDelivery) (int, error)
+
+func (f senderFunc) Send(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) (int, error) {
+	return f(delivery)
+}
+
+func TestCreateSubscription(t *testing.T) {
+	// Success case
+	t.Run("successful", func(t *testing.T) {
+		repo := &mockWebhookRepo{}
+		service := NewWebhookService(repo)
+
+		subscription, secret, err := service.CreateSubscription(context.Background(), "https://partner.example.com/hooks", []string{EventCustomerCreated})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), subscription.ID)
+		assert.Len(t, secret, len("whsec_")+64)
+	})
+
+	// Failure case
+	t.Run("(fail) invalid url", func(t *testing.T) {
+		service := NewWebhookService(&mockWebhookRepo{})
+
+		for _, url := range []string{"", "partner.example.com/hooks", "ftp://partner.example.com", "https://"} {
+			_, _, err := service.CreateSubscription(context.Background(), url, []string{EventCustomerCreated})
+			assert.Equal(t, ErrInvalidWebhookURL, err, url)
+		}
+	})
+
+	t.Run("(fail) address of our own network", func(t *testing.T) {
+		service := NewWebhookService(&mockWebhookRepo{})
+
+		for _, url := range []string{
+			"http://127.0.0.1/hooks", "http://localhost:8080/hooks", "http://api.localhost/hooks", "http://[::1]/hooks",
+			"http://169.254.169.254/latest/meta-data", "http://10.0.0.5/hooks", "http://192.168.1.1/hooks",
+			"http://0.0.0.0/hooks", "http://[::ffff:127.0.0.1]/hooks", "http://[fd00::1]/hooks",
+		} {
+			_, _, err := service.CreateSubscription(context.Background(), url, []string{EventCustomerCreated})
+			assert.Equal(t, ErrWebhookAddressForbidden, err, url)
+		}
+	})
+
+	t.Run("(fail) invalid event types", func(t *testing.T) {
+		service := NewWebhookService(&mockWebhookRepo{})
+
+		_, _, err := service.CreateSubscription(context.Background(), "https://partner.example.com", nil)
+		assert.Equal(t, ErrInvalidWebhookEventType, err)
+
+		_, _, err = service.CreateSubscription(context.Background(), "https://partner.example.com", []string{"CustomerRenamed"})
+		assert.Equal(t, ErrInvalidWebhookEventType, err)
+	})
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setupDispatcher := func(repo *mockWebhookRepo, send senderFunc) *WebhookDispatcher {
+		dispatcher := NewWebhookDispatcher(repo, send, WebhookDispatcherConfig{MinBackoff: time.Minute, MaxBackoff: time.Hour, MaxAttempts: 3})
+		dispatcher.now = func() time.Time { return start }
+		return dispatcher
+	}
+	subscriptions := []WebhookSubscription{{ID: 1, URL: "https://partner.example.com"}}
+
+	// Success case
+	t.Run("successful delivery", func(t *testing.T) {
+		repo := &mockWebhookRepo{subscriptions: subscriptions, deliveries: []WebhookDelivery{{ID: 1, SubscriptionID: 1}}}
+		dispatcher := setupDispatcher(repo, func(delivery *WebhookDelivery) (int, error) {
+			return 204, nil
+		})
+
+		_, err := dispatcher.DispatchOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, start, *repo.updated[0].DeliveredAt)
+		assert.Equal(t, 1, repo.updated[0].Attempts)
+	})
+
+	// Failure case
+	t.Run("(fail) retried with exponential backoff until dead", func(t *testing.T) {
+		repo := &mockWebhookRepo{subscriptions: subscriptions, deliveries: []WebhookDelivery{
+			{ID: 1, SubscriptionID: 1},
+			{ID: 2, SubscriptionID: 1, Attempts: 1},
+			{ID: 3, SubscriptionID: 1, Attempts: 2},
+		}}
+		dispatcher := setupDispatcher(repo, func(delivery *WebhookDelivery) (int, error) {
+			return 503, errors.New("webhook receiver responded with status 503")
+		})
+
+		_, err := dispatcher.DispatchOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, start.Add(time.Minute), repo.updated[0].NextAttemptAt)
+		assert.Equal(t, start.Add(2*time.Minute), repo.updated[1].NextAttemptAt)
+		assert.Nil(t, repo.updated[1].DeadAt)
+		assert.Equal(t, start, *repo.updated[2].DeadAt)
+		assert.Equal(t, 503, repo.updated[2].LastStatusCode)
+	})
+
+	t.Run("(fail) redeliver a delivery that is not dead", func(t *testing.T) {
+		repo := &mockWebhookRepo{deliveries: []WebhookDelivery{{ID: 1}}}
+		service := NewWebhookService(repo)
+
+		assert.Equal(t, ErrWebhookDeliveryNotDead, service.Redeliver(context.Background(), 1))
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+var errCustomersUsage = errors.New("usage: customers get <id> | list | create -name <name> -age <age> | delete <id> [-tenant <tenant>]")
+
+// runCustomers runs "customers get|list|create|delete" through the CustomerService of the configured database,
+// the customers are printed as JSON
+func runCustomers(ctx context.Context, cli *cli, args []string) error {
+	if len(args) == 0 {
+		return errCustomersUsage
+	}
+	flags := cli.newFlagSet("customers " + args[0])
+	tenantId := tenantFlag(flags)
+	name := flags.String("name", "", "name of the customer to create")
+	age := flags.Uint("age", 0, "age of the customer to create")
+	positional, err := parseFlags(flags, args[1:])
+	if err != nil {
+		return err
+	}
+
+	customerService, err := cli.customerService(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = core.WithTenant(ctx, *tenantId)
+	encoder := json.NewEncoder(cli.out)
+	encoder.SetIndent("", "  ")
+
+	switch args[0] {
+	case "get":
+		customerId, err := customerIdArg(positional)
+		if err != nil {
+			return err
+		}
+		customer, err := customerService.GetCustomerById(ctx, customerId)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(customer)
+
+	case "list":
+		customers, err := customerService.GetAllCustomer(ctx)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(customers)
+
+	case "create":
+		customer := core.Customer{Name: *name, Age: *age}
+		if err := customerService.ValidateName(customer.Name); err != nil {
+			return err
+		}
+		if err := customerService.CreateCustomer(ctx, customer); err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.out, "customer %q created\n", customer.Name)
+		return nil
+
+	case "delete":
+		customerId, err := customerIdArg(positional)
+		if err != nil {
+			return err
+		}
+		// Search the customer first, deleting a missing one is no error of the repository
+		if err := customerService.SearchCustomerById(ctx, customerId); err != nil {
+			return err
+		}
+		if err := customerService.DeleteCustomer(ctx, customerId); err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.out, "customer %d deleted\n", customerId)
+		return nil
+
+	default:
+		return errCustomersUsage
+	}
+}
+
+// customerIdArg reads the id of "customers get" and "customers delete"
+func customerIdArg(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, errCustomersUsage
+	}
+	customerId, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid customer id %q", args[0])
+	}
+	return uint(customerId), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportFormats are the formats of -format
+var exportFormats = map[string]string{
+	"json":   fiber.MIMEApplicationJSON,
+	"ndjson": adapters.MIMEApplicationNDJSON,
+	"csv":    adapters.MIMETextCSV,
+}
+
+// runExport runs "export [-format json|ndjson|csv] [-file <path>] [-tenant <tenant>]", it writes all customers to
+// the file, or to stdout without one
+func runExport(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet("export")
+	format := flags.String("format", "json", "json, ndjson or csv")
+	filePath := flags.String("file", "", "path of the file to write, stdout when empty")
+	tenantId := tenantFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	mime, ok := exportFormats[*format]
+	if !ok {
+		return fmt.Errorf("format must be json, ndjson or csv, not %q", *format)
+	}
+
+	customerService, err := cli.customerService(ctx)
+	if err != nil {
+		return err
+	}
+	stream, err := customerService.ExportCustomers(core.WithTenant(ctx, *tenantId))
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cli.out
+	if *filePath != "" {
+		file, err := os.Create(*filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return adapters.WriteCustomers(bufio.NewWriter(out), mime, stream)
+}
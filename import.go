@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// runImport runs "import -file <csv> [-tenant <tenant>] [-dry-run]", it imports customers from a CSV file with the
+// columns name and age straight into the database, also files larger than the body limit of the server
+func runImport(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet("import")
+	filePath := flags.String("file", "", "path of the CSV file to import")
+	tenantId := tenantFlag(flags)
+	dryRun := flags.Bool("dry-run", false, "only validate the rows, nothing is written")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *filePath == "" {
+		flags.Usage()
+		return errors.New("-file is required")
+	}
+
+	// Open the csv file and read its header
+	file, err := os.Open(*filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	source, err := adapters.NewCSVCustomerSource(file)
+	if err != nil {
+		return err
+	}
+
+	customerService, err := cli.customerService(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := customerService.ImportCustomers(core.WithTenant(ctx, *tenantId), source, *dryRun)
+	if report != nil {
+		printImportReport(cli.out, report)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows were not imported", report.Failed)
+	}
+
+	return nil
+}
+
+func printImportReport(out io.Writer, report *core.ImportReport) {
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(out, "line %d: %v\n", rowErr.Line, rowErr.Err)
+	}
+	if report.ErrorsTruncated {
+		fmt.Fprintf(out, "only the first %d errors are listed\n", core.MaxImportErrors)
+	}
+
+	if report.DryRun {
+		fmt.Fprintf(out, "dry run: %d rows, %d valid, %d invalid\n", report.Rows, report.Valid, report.Failed)
+		return
+	}
+	fmt.Fprintf(out, "%d rows, %d imported, %d failed\n", report.Rows, report.Imported, report.Failed)
+}
@@ -0,0 +1,199 @@
+// Package lifecycle runs the HTTP server with the background workers of the service and shuts them down gracefully:
+// on a signal the long-lived requests are told to end, the server stops accepting connections and drains the
+// requests in flight, then the workers are stopped in order and the resources, such as the database, are closed last
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the timeouts of the shutdown when none is configured
+const (
+	DefaultShutdownTimeout   = 15 * time.Second
+	DefaultWorkerStopTimeout = 10 * time.Second
+)
+
+// Server is the HTTP server, *fiber.App satisfies it
+type Server interface {
+	Listen(addr string) error
+	ShutdownWithContext(ctx context.Context) error
+}
+
+// Config of the Manager
+type Config struct {
+	// Addr the server listens on
+	Addr string
+	// ShutdownTimeout bounds draining the requests
+	ShutdownTimeout time.Duration
+	// WorkerStopTimeout bounds stopping the workers, it starts once the requests are drained, so a slow drain
+	// does not leave the workers without time
+	WorkerStopTimeout time.Duration
+	// Logger logs the start and the shutdown, the default logger of slog when nil
+	Logger *slog.Logger
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// ErrShuttingDown is the health of the workers once the shutdown started, so no new requests are routed here
+var ErrShuttingDown = errors.New("shutting down")
+
+// Manager starts the server and the workers and stops them again
+type Manager struct {
+	server       Server
+	config       Config
+	workers      []*worker
+	closers      []closer
+	started      atomic.Bool
+	shuttingDown atomic.Bool
+	stopping     context.Context
+	stop         context.CancelFunc
+}
+
+func NewManager(server Server, config Config) *Manager {
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if config.WorkerStopTimeout <= 0 {
+		config.WorkerStopTimeout = DefaultWorkerStopTimeout
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	stopping, stop := context.WithCancel(context.Background())
+	return &Manager{server: server, config: config, stopping: stopping, stop: stop}
+}
+
+// Stopping is canceled when the shutdown starts. Requests that only end when their client goes away, such as
+// event streams, end on it, the server waits for them before the workers stop and would wait until the timeout
+func (m *Manager) Stopping() context.Context {
+	return m.stopping
+}
+
+// AddWorker adds a background worker, run must return once its ctx is canceled. Workers are stopped one after
+// the other in the order they were added, so a worker producing work for another one is added first
+func (m *Manager) AddWorker(name string, run func(ctx context.Context)) {
+	m.workers = append(m.workers, &worker{name: name, run: run})
+}
+
+// AddCloser adds a resource closed after the server and every worker stopped, in reverse order like defer
+func (m *Manager) AddCloser(name string, close func() error) {
+	m.closers = append(m.closers, closer{name: name, close: close})
+}
+
+// Run starts the workers and the server and blocks until ctx is canceled (by a signal) or the server fails, then
+// it shuts everything down. The errors of the server and of the shutdown are returned together
+func (m *Manager) Run(ctx context.Context) error {
+	for _, w := range m.workers {
+		// workers get a context of their own, they keep running while the requests are drained
+		workerCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+		w.stop, w.done = stop, make(chan struct{})
+		go func(w *worker) {
+			defer close(w.done)
+			w.run(workerCtx)
+		}(w)
+	}
+	m.started.Store(true)
+
+	listenErr := make(chan error, 1)
+	m.config.Logger.Info("server listening", "addr", m.config.Addr, "workers", len(m.workers))
+	go func() {
+		listenErr <- m.server.Listen(m.config.Addr)
+	}()
+
+	var errs []error
+	serverStopped := false
+	select {
+	case <-ctx.Done():
+	case err := <-listenErr:
+		// the server stopped by itself, e.g. the address is in use
+		serverStopped = true
+		if err != nil {
+			errs = append(errs, fmt.Errorf("server: %w", err))
+		}
+	}
+
+	m.config.Logger.Info("shutting down", "timeout", m.config.ShutdownTimeout, "worker_stop_timeout", m.config.WorkerStopTimeout)
+	errs = append(errs, m.shutdown(context.WithoutCancel(ctx), serverStopped, listenErr)...)
+
+	err := errors.Join(errs...)
+	if err != nil {
+		m.config.Logger.Error("shutdown failed", "error", err)
+		return err
+	}
+	m.config.Logger.Info("shutdown complete")
+	return nil
+}
+
+// shutdown drains the server, stops the workers and closes the resources, each step with a timeout of its own
+func (m *Manager) shutdown(ctx context.Context, serverStopped bool, listenErr <-chan error) []error {
+	var errs []error
+	m.shuttingDown.Store(true)
+	m.stop()
+
+	// stop accepting connections and wait for the requests in flight
+	if !serverStopped {
+		drainCtx, cancel := context.WithTimeout(ctx, m.config.ShutdownTimeout)
+		if err := m.server.ShutdownWithContext(drainCtx); err != nil {
+			errs = append(errs, fmt.Errorf("drain requests: %w", err))
+		}
+		cancel()
+		if err := <-listenErr; err != nil {
+			errs = append(errs, fmt.Errorf("server: %w", err))
+		}
+	}
+
+	workerCtx, cancel := context.WithTimeout(ctx, m.config.WorkerStopTimeout)
+	defer cancel()
+	for _, w := range m.workers {
+		w.stop()
+		select {
+		case <-w.done:
+		case <-workerCtx.Done():
+			errs = append(errs, fmt.Errorf("worker %s did not stop: %w", w.name, workerCtx.Err()))
+		}
+	}
+
+	// closers run even after a timeout, the process is about to exit anyway
+	for index := len(m.closers) - 1; index >= 0; index-- {
+		if err := m.closers[index].close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", m.closers[index].name, err))
+		}
+	}
+
+	return errs
+}
+
+// CheckWorkers is the health of the workers, it fails when one of them stopped before the shutdown
+func (m *Manager) CheckWorkers(ctx context.Context) error {
+	if m.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if !m.started.Load() {
+		return errors.New("workers not started")
+	}
+
+	var errs []error
+	for _, w := range m.workers {
+		select {
+		case <-w.done:
+			errs = append(errs, fmt.Errorf("worker %s stopped", w.name))
+		default:
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,229 @@
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockServer listens until it is shut down, or fails right away with listenErr
+type mockServer struct {
+	listenErr error
+	stopped   chan struct{}
+	onStop    func()
+}
+
+func newMockServer() *mockServer {
+	return &mockServer{stopped: make(chan struct{})}
+}
+
+func (s *mockServer) Listen(addr string) error {
+	if s.listenErr != nil {
+		return s.listenErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *mockServer) ShutdownWithContext(ctx context.Context) error {
+	if s.onStop != nil {
+		s.onStop()
+	}
+	close(s.stopped)
+	return nil
+}
+
+// listenerServer is a fiber app serving on a listener opened by the test, so the test knows its address
+type listenerServer struct {
+	*fiber.App
+	listener net.Listener
+}
+
+func (s listenerServer) Listen(addr string) error {
+	return s.App.Listener(s.listener)
+}
+
+// recorder records the order in which the server, workers and closers stop
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) worker(name string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		<-ctx.Done()
+		r.add("stop " + name)
+	}
+}
+
+func TestManagerRun(t *testing.T) {
+	// Success case
+	t.Run("successful shutdown in order", func(t *testing.T) {
+		events := &recorder{}
+		server := newMockServer()
+		server.onStop = func() { events.add("drain server") }
+		manager := NewManager(server, Config{Addr: ":0", ShutdownTimeout: time.Second})
+		manager.AddWorker("relay", events.worker("relay"))
+		manager.AddWorker("dispatcher", events.worker("dispatcher"))
+		manager.AddCloser("cache", func() error { events.add("close cache"); return nil })
+		manager.AddCloser("database", func() error { events.add("close database"); return nil })
+
+		// cancel like a signal does
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		// call Run() and check Error
+		err := manager.Run(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"drain server", "stop relay", "stop dispatcher", "close database", "close cache"}, events.events)
+	})
+
+	t.Run("successful shutdown ends a long-lived stream", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		app := fiber.New(fiber.Config{DisableStartupMessage: true})
+		manager := NewManager(listenerServer{App: app, listener: listener}, Config{ShutdownTimeout: 5 * time.Second, WorkerStopTimeout: time.Second})
+		events := &recorder{}
+		manager.AddWorker("relay", events.worker("relay"))
+
+		// the stream sends a heartbeat until the shutdown starts, like the event stream of the customers
+		app.Get("/stream", func(c *fiber.Ctx) error {
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				for {
+					w.WriteString(": keep-alive\n\n")
+					if err := w.Flush(); err != nil {
+						return
+					}
+					select {
+					case <-manager.Stopping().Done():
+						return
+					case <-time.After(10 * time.Millisecond):
+					}
+				}
+			})
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- manager.Run(ctx) }()
+
+		resp, err := http.Get("http://" + listener.Addr().String() + "/stream")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, ": keep-alive\n", line)
+
+		// the shutdown does not wait for the client of the stream until the timeout
+		started := time.Now()
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("shutdown waited for the stream")
+		}
+		assert.Less(t, time.Since(started), time.Second)
+		assert.Equal(t, []string{"stop relay"}, events.events)
+		_, err = io.Copy(io.Discard, resp.Body)
+		assert.NoError(t, err)
+	})
+
+	t.Run("successful workers stop after a slow drain", func(t *testing.T) {
+		events := &recorder{}
+		server := newMockServer()
+		// the requests take longer to drain than the timeout allows
+		server.onStop = func() { time.Sleep(30 * time.Millisecond) }
+		manager := NewManager(server, Config{Addr: ":0", ShutdownTimeout: 20 * time.Millisecond, WorkerStopTimeout: time.Second})
+		manager.AddWorker("relay", func(ctx context.Context) {
+			<-ctx.Done()
+			time.Sleep(5 * time.Millisecond)
+			events.add("stop relay")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// the workers have a timeout of their own and check Error
+		err := manager.Run(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"stop relay"}, events.events)
+	})
+
+	// Failure case
+	t.Run("(fail) listen error stops the workers", func(t *testing.T) {
+		events := &recorder{}
+		server := newMockServer()
+		server.listenErr = errors.New("address already in use")
+		manager := NewManager(server, Config{Addr: ":0"})
+		manager.AddWorker("relay", events.worker("relay"))
+		manager.AddCloser("database", func() error { events.add("close database"); return nil })
+
+		err := manager.Run(context.Background())
+		assert.EqualError(t, err, "server: address already in use")
+		assert.Equal(t, []string{"stop relay", "close database"}, events.events)
+	})
+
+	t.Run("(fail) worker not stopping in time", func(t *testing.T) {
+		events := &recorder{}
+		release := make(chan struct{})
+		defer close(release)
+		manager := NewManager(newMockServer(), Config{Addr: ":0", WorkerStopTimeout: 20 * time.Millisecond})
+		manager.AddWorker("stuck", func(ctx context.Context) { <-release })
+		manager.AddCloser("database", func() error { events.add("close database"); return errors.New("already closed") })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := manager.Run(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "worker stuck did not stop")
+		assert.ErrorContains(t, err, "close database: already closed")
+		// resources are closed after a timeout too
+		assert.Equal(t, []string{"close database"}, events.events)
+	})
+}
+
+func TestManagerCheckWorkers(t *testing.T) {
+	server := newMockServer()
+	manager := NewManager(server, Config{Addr: ":0", ShutdownTimeout: time.Second})
+	crashed := make(chan struct{})
+	manager.AddWorker("relay", func(ctx context.Context) { <-ctx.Done() })
+	manager.AddWorker("dispatcher", func(ctx context.Context) { <-crashed })
+	assert.EqualError(t, manager.CheckWorkers(context.Background()), "workers not started")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- manager.Run(ctx) }()
+
+	// Success case
+	assert.Eventually(t, func() bool { return manager.CheckWorkers(context.Background()) == nil }, time.Second, time.Millisecond)
+
+	// Failure case
+	close(crashed)
+	assert.Eventually(t, func() bool {
+		err := manager.CheckWorkers(context.Background())
+		return err != nil && err.Error() == "worker dispatcher stopped"
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.ErrorIs(t, manager.CheckWorkers(context.Background()), ErrShuttingDown)
+}
@@ -1,40 +1,74 @@
 package main
 
 import (
-	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fiatfour/itmx-crud-hex/config"
 	"github.com/fiatfour/itmx-crud-hex/core"
-	"github.com/gofiber/fiber/v2"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
-func main() {
-	// Initialize a new instance of a Fiber application
-	app := fiber.New()
+// command is a subcommand of the binary, it gets the arguments after its name
+type command struct {
+	usage string
+	run   func(ctx context.Context, cli *cli, args []string) error
+}
+
+// commands of the binary, serve runs when no command is given
+var commands = map[string]command{
+	"serve":     {usage: "serve", run: runServe},
+	"migrate":   {usage: "migrate up|down|status", run: runMigrate},
+	"seed":      {usage: "seed -file <fixtures> [-tenant <tenant>]", run: runSeed},
+	"import":    {usage: "import -file <csv> [-tenant <tenant>] [-dry-run]", run: runImport},
+	"export":    {usage: "export [-format json|ndjson|csv] [-file <path>] [-tenant <tenant>]", run: runExport},
+	"customers": {usage: "customers get <id> | list | create -name <name> -age <age> | delete <id> [-tenant <tenant>]", run: runCustomers},
+	"config":    {usage: "config print", run: runConfig},
+}
+
+// commandOrder is the order of the commands in the usage
+var commandOrder = []string{"serve", "migrate", "seed", "import", "export", "customers", "config"}
 
-	// Initialize the database connection
-	db, err := gorm.Open(sqlite.Open("customers.db"), &gorm.Config{})
+func main() {
+	// Load the configuration from defaults, config file, environment and flags, the arguments after the flags
+	// select a command
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv)
 	if err != nil {
-		panic("failed to connect database")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	os.Exit(run(context.Background(), cfg, args, os.Stdout, os.Stderr))
+}
+
+// run runs the command of args, or the server without one, and returns the exit code of the binary
+func run(ctx context.Context, cfg *config.Config, args []string, stdout io.Writer, stderr io.Writer) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		printUsage(stderr)
+		return 2
 	}
 
-	// Migrate the schema and insert rows of Customer
-	db.Migrator().CreateTable(&core.Customer{})
-	db.Create(&core.Customer{Name: "Fiat", Age: 24})
-	db.Create(&core.Customer{Name: "Anfat Nilaingan", Age: 40})
-
-	// Set up the core service and adapters
-	customerRepo := adapters.NewGormCustomerRepository(db)
-	customerService := core.NewCustomerService(customerRepo)
-	customerHandler := adapters.NewHttpCustomerHandler(customerService)
-
-	// Define routes
-	app.Post("/customers", customerHandler.CreateCustomerHandler)
-	app.Get("/customers/:id", customerHandler.GetCustomerHandler)
-	app.Get("/customers", customerHandler.GetAllCustomerHandler)
-	app.Put("/customers/:id", customerHandler.UpdateCustomerHandler)
-	app.Delete("/customers/:id", customerHandler.DeleteCustomerHandler)
-
-	// Start the server
-	app.Listen("localhost:8080")
+	// logs go to stderr, stdout is the output of the commands
+	logger := newLogger(cfg.Log, stderr)
+	cli := &cli{cfg: cfg, out: stdout, logger: logger}
+	defer cli.close()
+	if err := command.run(core.WithLogger(ctx, logger), cli, args); err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: itmx-crud-hex [-config <file>] [-<setting> <value>...] <command>")
+	fmt.Fprintln(w, "commands:")
+	for _, name := range commandOrder {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand runs the binary with args against the database of cfg and returns its exit code and output
+func runCommand(cfg *config.Config, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), cfg, args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	newConfig := func(t *testing.T) *config.Config {
+		cfg := config.Default()
+		cfg.Database.DSN = filepath.Join(t.TempDir(), "customers.db")
+		return &cfg
+	}
+
+	// Success case
+	t.Run("customers", func(t *testing.T) {
+		cfg := newConfig(t)
+		code, _, stderr := runCommand(cfg, "migrate", "up")
+		assert.Equal(t, 0, code, stderr)
+
+		code, stdout, stderr := runCommand(cfg, "customers", "create", "-name", "Alice", "-age", "30")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, `customer "Alice" created`)
+
+		code, stdout, _ = runCommand(cfg, "customers", "list")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, `"Name": "Alice"`)
+
+		code, stdout, _ = runCommand(cfg, "customers", "get", "1")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, `"Age": 30`)
+
+		// customers of another tenant are not listed
+		code, stdout, _ = runCommand(cfg, "customers", "list", "-tenant", "other")
+		assert.Equal(t, 0, code)
+		assert.NotContains(t, stdout, "Alice")
+
+		// the flags of the usage come after the id
+		code, _, stderr = runCommand(cfg, "customers", "create", "-name", "Bob", "-age", "40", "-tenant", "other")
+		assert.Equal(t, 0, code, stderr)
+		code, stdout, stderr = runCommand(cfg, "customers", "get", "2", "-tenant", "other")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, `"Name": "Bob"`)
+		code, stdout, stderr = runCommand(cfg, "customers", "delete", "2", "-tenant", "other")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, "customer 2 deleted")
+
+		code, stdout, _ = runCommand(cfg, "customers", "delete", "1")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, "customer 1 deleted")
+
+		code, _, stderr = runCommand(cfg, "customers", "get", "1")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "customers failed")
+	})
+
+	t.Run("import and export", func(t *testing.T) {
+		cfg := newConfig(t)
+		runCommand(cfg, "migrate", "up")
+		csvPath := filepath.Join(t.TempDir(), "customers.csv")
+		assert.NoError(t, os.WriteFile(csvPath, []byte("name,age\nAlice,30\nBob,40\n"), 0o644))
+
+		code, stdout, stderr := runCommand(cfg, "import", "-file", csvPath)
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, "2 rows, 2 imported, 0 failed")
+
+		code, stdout, stderr = runCommand(cfg, "export", "-format", "csv")
+		assert.Equal(t, 0, code, stderr)
+		assert.Equal(t, "id,name,age\n1,Alice,30\n2,Bob,40\n", stdout)
+	})
+
+	// Failure case
+	t.Run("(fail) unknown command", func(t *testing.T) {
+		code, _, stderr := runCommand(newConfig(t), "unknown")
+		assert.Equal(t, 2, code)
+		assert.Contains(t, stderr, "usage:")
+	})
+
+	t.Run("(fail) schema behind", func(t *testing.T) {
+		code, _, stderr := runCommand(newConfig(t), "customers", "list")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "migrate")
+	})
+
+	t.Run("(fail) invalid customer id", func(t *testing.T) {
+		cfg := newConfig(t)
+		runCommand(cfg, "migrate", "up")
+		code, _, stderr := runCommand(cfg, "customers", "get", "abc")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, `invalid customer id "abc"`)
+	})
+}
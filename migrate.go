@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fiatfour/itmx-crud-hex/migrations"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up|down|status")
+
+// runMigrate runs "migrate up", "migrate down" or "migrate status" on the configured database
+func runMigrate(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+	db, err := cli.database()
+	if err != nil {
+		return err
+	}
+	migrator := migrations.NewMigrator(db, migrations.All)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(cli.out, "applied %04d %s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(cli.out, "schema is up to date")
+		}
+		return err
+
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.out, "reverted %04d %s\n", reverted.Version, reverted.Name)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(cli.out, "%04d %-28s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+
+	default:
+		return errMigrateUsage
+	}
+}
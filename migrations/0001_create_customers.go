@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var createCustomers = Migration{
+	Version: 1,
+	Name:    "create_customers",
+	Up: func(tx *gorm.DB) error {
+		type Customer struct {
+			ID       uint   `gorm:"primaryKey"`
+			TenantID string `gorm:"not null;default:'';uniqueIndex:idx_customers_tenant_name"`
+			Name     string `gorm:"uniqueIndex:idx_customers_tenant_name"`
+			Age      uint
+		}
+		if !tx.Migrator().HasTable(&Customer{}) {
+			return createTables(tx, &Customer{})
+		}
+
+		// the first versions created customers with id, name and age only and allowed the same name twice, the
+		// customers go to the default tenant. A repeated name has no rename the name rules accept, so the
+		// migration stops and lists them to be renamed or deleted by hand
+		if !tx.Migrator().HasColumn(&Customer{}, "TenantID") {
+			if err := tx.Migrator().AddColumn(&Customer{}, "TenantID"); err != nil {
+				return err
+			}
+		}
+		if tx.Migrator().HasIndex(&Customer{}, "idx_customers_tenant_name") {
+			return nil
+		}
+		var duplicates []Customer
+		err := tx.Raw("SELECT id, tenant_id, name FROM customers c WHERE EXISTS " +
+			"(SELECT 1 FROM customers o WHERE o.tenant_id = c.tenant_id AND o.name = c.name AND o.id < c.id)").Scan(&duplicates).Error
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			ids := make([]string, len(duplicates))
+			for index, duplicate := range duplicates {
+				ids[index] = fmt.Sprintf("%d (%q)", duplicate.ID, duplicate.Name)
+			}
+			return fmt.Errorf("%w, rename or delete the customers %s", ErrDuplicateNames, strings.Join(ids, ", "))
+		}
+		return tx.Migrator().CreateIndex(&Customer{}, "idx_customers_tenant_name")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("customers")
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createApiKeys = Migration{
+	Version: 2,
+	Name:    "create_api_keys",
+	Up: func(tx *gorm.DB) error {
+		type ApiKey struct {
+			ID         uint   `gorm:"primaryKey"`
+			TenantID   string `gorm:"not null;default:''"`
+			Name       string
+			Prefix     string `gorm:"uniqueIndex"`
+			SecretHash string
+			Scopes     string
+			ExpiresAt  *time.Time
+			LastUsedAt *time.Time
+			RevokedAt  *time.Time
+			CreatedAt  time.Time
+		}
+		return createTables(tx, &ApiKey{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("api_keys")
+	},
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createIdempotencyRecords = Migration{
+	Version: 3,
+	Name:    "create_idempotency_records",
+	Up: func(tx *gorm.DB) error {
+		type IdempotencyRecord struct {
+			Key         string `gorm:"column:idempotency_key;primaryKey"`
+			Fingerprint string
+			Completed   bool
+			StatusCode  int
+			ContentType string
+			Body        []byte
+			ExpiresAt   time.Time `gorm:"index"`
+		}
+		return createTables(tx, &IdempotencyRecord{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("idempotency_records")
+	},
+}
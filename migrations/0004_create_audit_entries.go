@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createAuditEntries = Migration{
+	Version: 4,
+	Name:    "create_audit_entries",
+	Up: func(tx *gorm.DB) error {
+		type AuditEntry struct {
+			ID         uint      `gorm:"primaryKey"`
+			TenantID   string    `gorm:"not null;default:'';index"`
+			Actor      string    `gorm:"not null;default:''"`
+			Action     string    `gorm:"not null"`
+			CustomerID uint      `gorm:"index"`
+			CreatedAt  time.Time `gorm:"index"`
+		}
+		return createTables(tx, &AuditEntry{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("audit_entries")
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createOutboxMessages = Migration{
+	Version: 5,
+	Name:    "create_outbox_messages",
+	Up: func(tx *gorm.DB) error {
+		type OutboxMessage struct {
+			ID            uint      `gorm:"primaryKey"`
+			TenantID      string    `gorm:"not null;default:'';index"`
+			EventType     string    `gorm:"not null"`
+			CustomerID    uint      `gorm:"index"`
+			Payload       []byte    `gorm:"not null"`
+			OccurredAt    time.Time `gorm:"not null"`
+			Attempts      int       `gorm:"not null;default:0"`
+			NextAttemptAt time.Time `gorm:"index"`
+			LastError     string
+			PublishedAt   *time.Time `gorm:"index"`
+		}
+		return createTables(tx, &OutboxMessage{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("outbox_messages")
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createWebhooks = Migration{
+	Version: 6,
+	Name:    "create_webhooks",
+	Up: func(tx *gorm.DB) error {
+		type WebhookSubscription struct {
+			ID         uint   `gorm:"primaryKey"`
+			TenantID   string `gorm:"not null;default:'';index"`
+			URL        string `gorm:"not null"`
+			EventTypes string
+			Secret     string `gorm:"not null"`
+			CreatedAt  time.Time
+		}
+		type WebhookDelivery struct {
+			ID             uint   `gorm:"primaryKey"`
+			TenantID       string `gorm:"not null;default:'';index"`
+			SubscriptionID uint   `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_message"`
+			MessageID      uint   `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_message"`
+			EventType      string `gorm:"not null"`
+			Payload        []byte `gorm:"not null"`
+			OccurredAt     time.Time
+			Attempts       int       `gorm:"not null;default:0"`
+			NextAttemptAt  time.Time `gorm:"index"`
+			LastError      string
+			LastStatusCode int
+			DeliveredAt    *time.Time
+			DeadAt         *time.Time `gorm:"index"`
+			CreatedAt      time.Time
+		}
+		return createTables(tx, &WebhookSubscription{}, &WebhookDelivery{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("webhook_deliveries", "webhook_subscriptions")
+	},
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createChangeRecords = Migration{
+	Version: 7,
+	Name:    "create_change_records",
+	Up: func(tx *gorm.DB) error {
+		type ChangeRecord struct {
+			Sequence   uint   `gorm:"primaryKey;autoIncrement:false;index:idx_change_records_tenant_sequence,priority:2"`
+			TenantID   string `gorm:"not null;default:'';index:idx_change_records_tenant_sequence,priority:1"`
+			Operation  string `gorm:"not null"`
+			CustomerID uint   `gorm:"not null"`
+			Customer   string
+			Deleted    bool      `gorm:"not null;default:false"`
+			ChangedAt  time.Time `gorm:"not null"`
+		}
+		return createTables(tx, &ChangeRecord{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("change_records")
+	},
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+var createChangeSequences = Migration{
+	Version: 8,
+	Name:    "create_change_sequences",
+	Up: func(tx *gorm.DB) error {
+		type ChangeSequence struct {
+			ID    uint `gorm:"primaryKey;autoIncrement:false"`
+			Value uint `gorm:"not null;default:0"`
+		}
+		if err := createTables(tx, &ChangeSequence{}); err != nil {
+			return err
+		}
+
+		// the counter goes on after the change records written before it existed
+		return tx.Exec("INSERT INTO change_sequences (id, value) SELECT 1, COALESCE(MAX(sequence), 0) FROM change_records " +
+			"WHERE NOT EXISTS (SELECT 1 FROM change_sequences)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("change_sequences")
+	},
+}
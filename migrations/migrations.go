@@ -0,0 +1,208 @@
+// Package migrations evolves the database schema with numbered migrations compiled into the binary. Every
+// migration declares the tables it creates as they were at that version, so later changes of the models in core
+// need a migration of their own. The applied versions are kept in the schema_migrations table
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one numbered step of the schema, Down reverts what Up did
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// All are the migrations of this version of the service in the order they are applied
+var All = []Migration{
+	createCustomers,
+	createApiKeys,
+	createIdempotencyRecords,
+	createAuditEntries,
+	createOutboxMessages,
+	createWebhooks,
+	createChangeRecords,
+	createChangeSequences,
+}
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind")
+	ErrNothingToMigrate = errors.New("no migration is applied")
+	ErrDuplicateNames   = errors.New("customers share a name")
+)
+
+// SchemaMigration is the row of an applied migration
+type SchemaMigration struct {
+	Version   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+// Status tells whether a migration is applied, AppliedAt is nil for a pending one
+type Status struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts migrations on db
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator runs migrations, their versions must grow in the order of the slice
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up applies the pending migrations in order and returns them, every migration runs in a transaction of its own,
+// so a failing one keeps the ones before it
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	// only Up writes the schema_migrations table, so the other methods stay read-only
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return []Migration{}, err
+		}
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return []Migration{}, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the last applied migration and returns it
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// revert the migration with the highest applied version
+	for index := len(m.migrations) - 1; index >= 0; index-- {
+		migration := m.migrations[index]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		return &migration, nil
+	}
+
+	return nil, ErrNothingToMigrate
+}
+
+// Status returns every migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return []Status{}, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for index, migration := range m.migrations {
+		statuses[index] = Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			statuses[index].AppliedAt = &appliedAt
+		}
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet in order
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return []Migration{}, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// CheckCurrent returns ErrSchemaBehind while migrations are pending, also on a database never migrated, the server
+// does not start on such a schema. It only reads, so the readiness check can call it
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migrations are pending, the next is %d %s", ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	return nil
+}
+
+// applied returns the applied versions, none when the schema_migrations table is missing
+func (m *Migrator) applied(ctx context.Context) (map[uint]time.Time, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return map[uint]time.Time{}, nil
+	}
+
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// createTables creates the tables of models that do not exist yet, so databases of versions before the migrations,
+// whose tables were created at startup, adopt the first migrations as they are
+func createTables(tx *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		if tx.Migrator().HasTable(model) {
+			continue
+		}
+		if err := tx.Migrator().CreateTable(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
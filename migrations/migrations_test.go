@@ -0,0 +1,206 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// models are the current models of the service, the migrations must create every column and index they declare
+var models = []interface{}{
+	&core.Customer{}, &core.ChangeRecord{}, &core.AuditEntry{}, &core.OutboxMessage{},
+	&core.WebhookSubscription{}, &core.WebhookDelivery{}, &core.ApiKey{}, &adapters.IdempotencyRecord{},
+	&adapters.ChangeSequence{},
+}
+
+// setupTestDB opens an empty in-memory database of its own for a test
+func setupTestDB(t *testing.T, name string) *gorm.DB {
+	db, err := adapters.OpenDatabase(adapters.DriverSQLite, "file:"+name+"?mode=memory&cache=shared")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return db
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	// Success case
+	t.Run("successful up creates the schema of the models", func(t *testing.T) {
+		db := setupTestDB(t, "up")
+		migrator := NewMigrator(db, All)
+
+		// Up() applies every migration once and check Error
+		applied, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, applied, len(All))
+		assert.NoError(t, migrator.CheckCurrent(ctx))
+
+		applied, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+
+		// every column and index of the models exists
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: db}
+			assert.NoError(t, stmt.Parse(model))
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName != "" {
+					assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+				}
+			}
+			for _, index := range stmt.Schema.ParseIndexes() {
+				assert.True(t, db.Migrator().HasIndex(model, index.Name), "%s %s", stmt.Schema.Table, index.Name)
+			}
+		}
+	})
+
+	t.Run("successful down and status", func(t *testing.T) {
+		db := setupTestDB(t, "down")
+		migrator := NewMigrator(db, All)
+		_, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+
+		// Down() reverts the last migration only and check Error
+		reverted, err := migrator.Down(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(8), reverted.Version)
+		assert.False(t, db.Migrator().HasTable(&adapters.ChangeSequence{}))
+		assert.True(t, db.Migrator().HasTable(&core.ChangeRecord{}))
+
+		statuses, err := migrator.Status(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, len(All))
+		assert.NotNil(t, statuses[6].AppliedAt)
+		assert.Nil(t, statuses[7].AppliedAt)
+		assert.ErrorIs(t, migrator.CheckCurrent(ctx), ErrSchemaBehind)
+		assert.Equal(t, "database schema is behind: 1 migrations are pending, the next is 8 create_change_sequences", migrator.CheckCurrent(ctx).Error())
+
+		for range All[:7] {
+			_, err := migrator.Down(ctx)
+			assert.NoError(t, err)
+		}
+		_, err = migrator.Down(ctx)
+		assert.Equal(t, ErrNothingToMigrate, err)
+		assert.False(t, db.Migrator().HasTable(&core.Customer{}))
+	})
+
+	t.Run("successful up adopts the tables created at startup by earlier versions", func(t *testing.T) {
+		db := setupTestDB(t, "adopt")
+		assert.NoError(t, db.AutoMigrate(&core.Customer{}, &core.ApiKey{}))
+		assert.NoError(t, db.Create(&core.Customer{Name: "Fiat", Age: 24}).Error)
+
+		_, err := NewMigrator(db, All).Up(ctx)
+		assert.NoError(t, err)
+
+		var count int64
+		db.Model(&core.Customer{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("successful up adopts the customers table of the baseline", func(t *testing.T) {
+		db := setupTestDB(t, "baseline")
+		assert.NoError(t, db.Exec("CREATE TABLE customers (id integer PRIMARY KEY AUTOINCREMENT, name text, age integer)").Error)
+		assert.NoError(t, db.Exec("INSERT INTO customers (name, age) VALUES ('Fiat', 24), ('Anfat', 30)").Error)
+
+		// Up() adds the tenant and the unique index of the name and check Error
+		_, err := NewMigrator(db, All).Up(ctx)
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasColumn(&core.Customer{}, "tenant_id"))
+		assert.True(t, db.Migrator().HasIndex(&core.Customer{}, "idx_customers_tenant_name"))
+
+		var customers []core.Customer
+		assert.NoError(t, db.Order("id").Find(&customers).Error)
+		assert.Equal(t, []core.Customer{{ID: 1, Name: "Fiat", Age: 24}, {ID: 2, Name: "Anfat", Age: 30}}, customers)
+		assert.Error(t, db.Create(&core.Customer{Name: "Fiat", Age: 50}).Error)
+	})
+
+	t.Run("successful change sequence goes on after the existing change records", func(t *testing.T) {
+		db := setupTestDB(t, "sequence")
+		_, err := NewMigrator(db, All[:7]).Up(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Create(&core.ChangeRecord{Sequence: 5, Operation: core.ChangeCreate, CustomerID: 1}).Error)
+
+		_, err = NewMigrator(db, All).Up(ctx)
+		assert.NoError(t, err)
+
+		var sequence adapters.ChangeSequence
+		assert.NoError(t, db.First(&sequence).Error)
+		assert.Equal(t, adapters.ChangeSequence{ID: 1, Value: 5}, sequence)
+	})
+
+	t.Run("successful change sequence is given out by the change log only", func(t *testing.T) {
+		db := setupTestDB(t, "no_autoincrement")
+		_, err := NewMigrator(db, All).Up(ctx)
+		assert.NoError(t, err)
+
+		// the sequence is no auto increment (serial on postgres), as declared by the model
+		var ddl string
+		assert.NoError(t, db.Raw("SELECT sql FROM sqlite_master WHERE name = ?", "change_records").Scan(&ddl).Error)
+		assert.NotContains(t, ddl, "AUTOINCREMENT")
+	})
+
+	t.Run("successful versions grow in order", func(t *testing.T) {
+		for index := 1; index < len(All); index++ {
+			assert.Greater(t, All[index].Version, All[index-1].Version)
+		}
+	})
+
+	// Failure case
+	t.Run("(fail) baseline customers sharing a name are listed", func(t *testing.T) {
+		db := setupTestDB(t, "duplicates")
+		assert.NoError(t, db.Exec("CREATE TABLE customers (id integer PRIMARY KEY AUTOINCREMENT, name text, age integer)").Error)
+		assert.NoError(t, db.Exec("INSERT INTO customers (name, age) VALUES ('Fiat', 24), ('Anfat', 30), ('Fiat', 40)").Error)
+
+		// Up() stops at the first migration and check Error
+		applied, err := NewMigrator(db, All).Up(ctx)
+		assert.Empty(t, applied)
+		assert.ErrorIs(t, err, ErrDuplicateNames)
+		assert.Equal(t, `migration 1 create_customers: customers share a name, rename or delete the customers 3 ("Fiat")`, err.Error())
+
+		// nothing is renamed
+		var names []string
+		assert.NoError(t, db.Table("customers").Order("id").Pluck("name", &names).Error)
+		assert.Equal(t, []string{"Fiat", "Anfat", "Fiat"}, names)
+	})
+
+	t.Run("(fail) check of a database never migrated creates nothing", func(t *testing.T) {
+		db := setupTestDB(t, "empty")
+
+		// CheckCurrent() reports every migration as pending and check Error
+		err := NewMigrator(db, All).CheckCurrent(ctx)
+		assert.ErrorIs(t, err, ErrSchemaBehind)
+		assert.Equal(t, "database schema is behind: 8 migrations are pending, the next is 1 create_customers", err.Error())
+		assert.False(t, db.Migrator().HasTable(&SchemaMigration{}))
+	})
+
+	t.Run("(fail) failing migration keeps the ones before it", func(t *testing.T) {
+		db := setupTestDB(t, "fail")
+		failing := Migration{
+			Version: 8,
+			Name:    "failing",
+			Up: func(tx *gorm.DB) error {
+				return errors.New("broken migration")
+			},
+			Down: func(tx *gorm.DB) error {
+				return nil
+			},
+		}
+		migrator := NewMigrator(db, append(All[:1:1], failing))
+
+		applied, err := migrator.Up(ctx)
+		assert.Len(t, applied, 1)
+		assert.Equal(t, "migration 8 failing: broken migration", err.Error())
+
+		pending, err := migrator.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{8}, []uint{pending[0].Version})
+	})
+}
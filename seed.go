@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/core"
+)
+
+// runSeed runs "seed -file <fixtures> [-tenant <tenant>]", it creates the customers of the fixture file that do not
+// exist yet. The server never seeds by itself
+func runSeed(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet("seed")
+	filePath := flags.String("file", "", "path of the YAML or JSON fixture file")
+	tenantId := tenantFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *filePath == "" {
+		flags.Usage()
+		return errors.New("-file is required")
+	}
+
+	customers, err := adapters.LoadCustomerFixtures(*filePath)
+	if err != nil {
+		return err
+	}
+
+	customerService, err := cli.customerService(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := core.SeedCustomers(core.WithTenant(ctx, *tenantId), customerService, customers)
+	fmt.Fprintf(cli.out, "%d customers created, %d existing\n", report.Created, report.Existing)
+	return err
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fiatfour/itmx-crud-hex/adapters"
+	"github.com/fiatfour/itmx-crud-hex/core"
+	"github.com/fiatfour/itmx-crud-hex/lifecycle"
+	"github.com/fiatfour/itmx-crud-hex/migrations"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// runServe runs "serve", the HTTP server of the customer API
+func runServe(ctx context.Context, cli *cli, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("serve takes no arguments, got %q", args)
+	}
+	cfg := cli.cfg
+
+	// Initialize a new instance of a Fiber application, the start is logged by the lifecycle
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+
+	// Initialize the database connection
+	db, err := cli.database()
+	if err != nil {
+		return fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	// Refuse to start on a schema that is behind the migrations of this binary
+	if err := migrations.NewMigrator(db, migrations.All).CheckCurrent(ctx); err != nil {
+		return fmt.Errorf("%w, run migrate up first", err)
+	}
+
+	// Load the JWKS used to verify bearer tokens
+	keySet, err := adapters.LoadJWKS(cfg.Auth.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("failed to load jwks: %w", err)
+	}
+
+	// Load the role-to-scope mapping checked on every customer operation
+	policy, err := adapters.LoadPolicyFile(cfg.Auth.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load policy: %w", err)
+	}
+
+	// Trace the requests through the service and the repository down to the SQL statements, the tracing
+	// middleware runs first so every other middleware and handler is part of the request span
+	tracerProvider, err := adapters.NewTracerProvider(ctx, cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	if err := db.Use(adapters.NewGormTracing(tracerProvider)); err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	app.Use(adapters.NewTracingMiddleware(tracerProvider))
+
+	// Correlate the logs of a request by its X-Request-ID and log every request once it is answered
+	app.Use(adapters.NewRequestIDMiddleware(cli.logger), adapters.NewAccessLogMiddleware())
+
+	// Metrics of the Go runtime, the process, the HTTP requests, the customer repository and the customers
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		adapters.NewCustomerCountCollector(db),
+	)
+	app.Use(adapters.NewMetricsMiddleware(registry))
+
+	// Set up the core service and adapters
+	repositoryMetrics := adapters.NewCustomerRepositoryMetrics(registry)
+	repositoryTracing := adapters.NewCustomerRepositoryTracing(tracerProvider)
+	customerRepo := repositoryTracing(repositoryMetrics(adapters.NewGormCustomerRepository(db)))
+	unitOfWork := adapters.NewGormUnitOfWork(db, repositoryMetrics, repositoryTracing)
+	customerService := core.NewAuthorizedCustomerService(adapters.NewTracingCustomerService(core.NewCustomerService(customerRepo, unitOfWork), tracerProvider), policy)
+	customerHandler := adapters.NewHttpCustomerHandler(customerService)
+	apiKeyRepo := adapters.NewGormApiKeyRepository(db)
+	apiKeyService := core.NewApiKeyService(apiKeyRepo)
+	apiKeyHandler := adapters.NewHttpApiKeyHandler(apiKeyService)
+
+	// Publish the customer events written to the outbox, stdout stands in for the message broker,
+	// webhook deliveries are queued by the relay and sent by the dispatcher
+	webhookRepo := adapters.NewGormWebhookRepository(db)
+	webhookHandler := adapters.NewHttpWebhookHandler(core.NewWebhookService(webhookRepo))
+	var publisher core.MultiPublisher
+	if cfg.Features.StdoutEvents {
+		publisher = append(publisher, adapters.NewWriterPublisher(os.Stdout))
+	}
+	if cfg.Features.Webhooks {
+		publisher = append(publisher, core.NewWebhookPublisher(webhookRepo))
+	}
+	outboxRelay := core.NewOutboxRelay(adapters.NewGormOutboxRepository(db), publisher, core.OutboxRelayConfig{})
+
+	// The lifecycle ends the event streams and drains the requests on SIGINT or SIGTERM, then stops the workers in
+	// the order they are added: the relay queues the deliveries the dispatcher sends. The database is closed last
+	lifecycleManager := lifecycle.NewManager(app, lifecycle.Config{
+		Addr: cfg.Server.Addr, ShutdownTimeout: cfg.Server.ShutdownTimeout, WorkerStopTimeout: cfg.Server.WorkerStopTimeout,
+		Logger: cli.logger,
+	})
+	customerHandler.EndStreamsOn(lifecycleManager.Stopping())
+	lifecycleManager.AddWorker("outbox relay", outboxRelay.Run)
+	if cfg.Features.Webhooks {
+		webhookDispatcher := core.NewWebhookDispatcher(webhookRepo, adapters.NewHTTPWebhookSender(nil), core.WebhookDispatcherConfig{})
+		lifecycleManager.AddWorker("webhook dispatcher", webhookDispatcher.Run)
+	}
+	// the metrics name every tenant, so Prometheus scrapes them from an address of their own
+	metricsListener, err := net.Listen("tcp", cfg.Server.MetricsAddr)
+	if err != nil {
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	lifecycleManager.AddWorker("metrics server", adapters.NewMetricsServer(metricsListener, registry))
+	idempotencyStore := adapters.NewGormIdempotencyStore(db)
+	lifecycleManager.AddWorker("idempotency cleanup", func(ctx context.Context) {
+		adapters.CleanupIdempotencyRecords(ctx, idempotencyStore, cfg.Idempotency.CleanupInterval)
+	})
+	// closers run in reverse order, the spans of the shutdown are flushed after the database is closed
+	lifecycleManager.AddCloser("tracer provider", func() error { return tracerProvider.Shutdown(context.Background()) })
+	lifecycleManager.AddCloser("database", cli.close)
+
+	// Readiness needs the database, the schema of this binary and running workers, liveness only the process
+	migrator := migrations.NewMigrator(db, migrations.All)
+	healthHandler := adapters.NewHttpHealthHandler(core.NewHealthService(core.DefaultHealthCheckTimeout,
+		core.NewDatabaseCheck(adapters.NewGormHealthRepository(db)),
+		core.HealthCheck{Name: "migrations", Check: migrator.CheckCurrent},
+		core.HealthCheck{Name: "workers", Check: lifecycleManager.CheckWorkers},
+	))
+
+	// Authenticate machine clients by X-API-Key and everyone else by bearer token
+	apiKeyAuth := adapters.NewApiKeyMiddleware(apiKeyService)
+	jwtAuth := adapters.NewJWTMiddleware(adapters.JWTConfig{KeySet: keySet, Issuer: cfg.Auth.Issuer, Audience: cfg.Auth.Audience})
+
+	// Limit every IP before its credentials are checked, so floods and credential guessing are limited too. After
+	// authentication every client (api key or IP) is limited per route group, writes get a stricter limit
+	rateLimitStore := adapters.NewMemoryRateLimitStore()
+	ipLimit := adapters.NewRateLimitMiddleware(adapters.RateLimitConfig{
+		Name: "ip", Limit: adapters.RateLimit{Limit: cfg.RateLimit.PerIP, Period: cfg.RateLimit.Period}, Store: rateLimitStore,
+		KeyFunc: adapters.RateLimitIPKey,
+	})
+	customersLimit := adapters.NewRateLimitMiddleware(adapters.RateLimitConfig{
+		Name: "customers", Limit: adapters.RateLimit{Limit: cfg.RateLimit.Customers, Period: cfg.RateLimit.Period}, Store: rateLimitStore,
+	})
+	customerWritesLimit := adapters.NewRateLimitMiddleware(adapters.RateLimitConfig{
+		Name: "customer-writes", Limit: adapters.RateLimit{Limit: cfg.RateLimit.CustomerWrites, Period: cfg.RateLimit.Period}, Store: rateLimitStore,
+	})
+	adminLimit := adapters.NewRateLimitMiddleware(adapters.RateLimitConfig{
+		Name: "admin", Limit: adapters.RateLimit{Limit: cfg.RateLimit.Admin, Period: cfg.RateLimit.Period}, Store: rateLimitStore,
+	})
+
+	// Replay the response of retried creates and updates sent with an Idempotency-Key
+	idempotency := adapters.NewIdempotencyMiddleware(adapters.IdempotencyConfig{
+		Store: idempotencyStore, TTL: cfg.Idempotency.TTL, LockTimeout: cfg.Idempotency.LockTimeout,
+	})
+
+	// Probes of the orchestrator, without authentication or rate limit
+	app.Get("/healthz", healthHandler.LivenessHandler)
+	app.Get("/readyz", healthHandler.ReadinessHandler)
+
+	// Define routes, every customer route requires an authenticated principal
+	customers := app.Group("/customers", ipLimit, apiKeyAuth, jwtAuth, customersLimit)
+	customers.Post("/", customerWritesLimit, idempotency, customerHandler.CreateCustomerHandler)
+	// the customers group middleware also runs for /customers:batch, its path starts with the group prefix
+	app.Post("/customers\\:batch", customerWritesLimit, idempotency, customerHandler.BatchCustomerHandler)
+	// files larger than the body limit of fiber are imported with the import command
+	app.Post("/customers\\:import", customerWritesLimit, customerHandler.ImportCustomersHandler)
+	customers.Get("/export", customerHandler.ExportCustomersHandler)
+	customers.Get("/stream", customerHandler.StreamCustomerEventsHandler)
+	customers.Get("/:id", customerHandler.GetCustomerHandler)
+	customers.Get("/", customerHandler.GetAllCustomerHandler)
+	customers.Put("/:id", customerWritesLimit, idempotency, customerHandler.UpdateCustomerHandler)
+	customers.Delete("/:id", customerWritesLimit, customerHandler.DeleteCustomerHandler)
+
+	// Sync jobs read the changes of customers since the last sequence they got
+	app.Get("/changes", ipLimit, apiKeyAuth, jwtAuth, customersLimit, customerHandler.GetChangesHandler)
+
+	// Admin routes to manage api keys, only for users with the admin role
+	apiKeys := app.Group("/admin/api-keys", ipLimit, jwtAuth, adminLimit, adapters.NewRequireRoleMiddleware("admin"))
+	apiKeys.Post("/", apiKeyHandler.IssueApiKeyHandler)
+	apiKeys.Get("/", apiKeyHandler.GetAllApiKeyHandler)
+	apiKeys.Delete("/:id", apiKeyHandler.RevokeApiKeyHandler)
+	apiKeys.Post("/:id/rotate", apiKeyHandler.RotateApiKeyHandler)
+
+	// Admin routes to manage the webhooks of the tenant of the admin
+	if cfg.Features.Webhooks {
+		webhooks := app.Group("/admin/webhooks", ipLimit, jwtAuth, adminLimit, adapters.NewRequireRoleMiddleware("admin"))
+		webhooks.Post("/", webhookHandler.CreateSubscriptionHandler)
+		webhooks.Get("/", webhookHandler.GetAllSubscriptionsHandler)
+		webhooks.Delete("/:id", webhookHandler.DeleteSubscriptionHandler)
+		webhooks.Get("/dead-letters", webhookHandler.GetDeadLettersHandler)
+		webhooks.Post("/deliveries/:id/redeliver", webhookHandler.RedeliverHandler)
+	}
+
+	// Start the server and the workers until a signal arrives
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return lifecycleManager.Run(ctx)
+}